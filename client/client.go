@@ -2,21 +2,25 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/golang/protobuf/jsonpb"
 	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand/v2"
 	"metrics/client/pb/pv"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -27,15 +31,27 @@ const serverAddress = "localhost:8080"
 const logFileName = "client/run_log"
 
 var (
-	totalRequests             int64
-	totalFailedRequests       int64
-	totalSuccessfulRequests   int64
+	// requestCounts counts the Export calls, which are accepted when they succeed, and
+	// dataPointCounts their data points, which are rejected when the call fails or by a partial
+	// success.
+	requestCounts             counts
+	dataPointCounts           counts
 	firstFailedRequestDetails string
 	lastFailedRequestDetails  string
-	firstFailedRequestOnce    sync.Once
-	lastFailedRequestMutex    sync.Mutex
+	failedRequestMutex        sync.Mutex // Guards the failed request details
+	serverVersion             string
 	gitCommitSha              string
 	buildTimeStamp            int64
+
+	// latencies records the latency of every counted Export call, and intervalLatencies those since
+	// the last interim report.
+	latencies         = newHistogram()
+	intervalLatencies = newHistogram()
+	statusCodes       statusCodeCounts
+	// partialSuccessResponses counts the successful calls whose response rejected data points.
+	partialSuccessResponses int64
+	// lateRequests counts the requests of an open-loop run sent late or not completed, see lateThreshold.
+	lateRequests int64
 )
 
 func getVersion(client pv.VersionServiceClient) {
@@ -44,71 +60,56 @@ func getVersion(client pv.VersionServiceClient) {
 	if err != nil {
 		log.Printf("Failed to get response: %v", err)
 	}
-	gitCommitSha = resp.GitCommitSha
-	buildTimeStamp = resp.BuildTimestamp
+	serverVersion = resp.GetVersion()
+	gitCommitSha = resp.GetGitCommitSha()
+	buildTimeStamp = resp.GetBuildTimestamp()
 }
 
-func sendRequests(client pb.MetricsServiceClient, requestJson string, wg *sync.WaitGroup, ctx context.Context, numConcurrentRequests int) {
-	defer wg.Done()
-
-	req := &pb.ExportMetricsServiceRequest{}
-	if err := jsonpb.UnmarshalString(requestJson, req); err != nil {
-		log.Printf("Error unmarshalling request: %v", err)
+// printStatus writes the status of the server, which tells what the server observed during the run.
+func printStatus(w io.Writer, client pv.StatusServiceClient, title string) {
+	resp, err := client.GetStatus(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		fmt.Fprintf(w, "%s: failed to get the server status: %v\n", title, err)
 		return
 	}
+	fmt.Fprintf(w, "%s:\n%s\n", title, protojson.MarshalOptions{Multiline: true}.Format(resp))
+}
 
-	requestCh := make(chan struct{}, numConcurrentRequests)
-	defer close(requestCh)
-
-	for i := 0; i < numConcurrentRequests; i++ {
-		requestCh <- struct{}{}
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			// If context is canceled, return immediately
-			return
-		case <-requestCh:
-			go func() {
-				defer func() {
-					requestCh <- struct{}{}
-				}()
-				resp, err := client.Export(context.Background(), req)
-				atomic.AddInt64(&totalRequests, 1)
-
-				if err != nil {
-					// Case 1: Error returned by the Export call
-					atomic.AddInt64(&totalFailedRequests, 1)
-					firstFailedRequestOnce.Do(func() {
-						firstFailedRequestDetails = fmt.Sprintf("Failed request details: %v", req)
-					})
-					lastFailedRequestMutex.Lock()
-					lastFailedRequestDetails = fmt.Sprintf("Failed request details: %v", req)
-					lastFailedRequestMutex.Unlock()
-					//log.Printf("Failed to get response: %v", err)
-				} else if resp.GetPartialSuccess() != nil {
-					// Case 2: Partial success (some data points were rejected)
-					rejectedDataPoints := resp.GetPartialSuccess().GetRejectedDataPoints()
-					// Assuming each Metric contains exactly one data point, which might not be true
-					totalSentDataPoints := int64(len(req.ResourceMetrics))
-					successfulDataPoints := totalSentDataPoints - rejectedDataPoints
-					atomic.AddInt64(&totalFailedRequests, rejectedDataPoints)
-					atomic.AddInt64(&totalSuccessfulRequests, successfulDataPoints)
-					firstFailedRequestOnce.Do(func() {
-						firstFailedRequestDetails = fmt.Sprintf("Partial error request details: %v", req)
-					})
-					lastFailedRequestMutex.Lock()
-					lastFailedRequestDetails = fmt.Sprintf("Failed request details: %v", req)
-					lastFailedRequestMutex.Unlock()
-				} else {
-					// Case 3: Successful response
-					atomic.AddInt64(&totalSuccessfulRequests, 1)
-					//log.Printf("Successful response received")
-				}
-			}()
-		}
+// recordResponse counts the outcome of an Export call, in the totals and in the stage it was sent in.
+func recordResponse(stats *stageStats, req *pb.ExportMetricsServiceRequest, resp *pb.ExportMetricsServiceResponse, err error) {
+	statusCodes.add(status.Code(err), 1)
+	dataPoints := countDataPoints(req)
+
+	var requestsAccepted, dataPointsAccepted int64
+	if err != nil {
+		// Case 1: Error returned by the Export call, none of the data points were accepted
+		recordFailedRequest("Failed request details: %v", req)
+	} else if resp.GetPartialSuccess() != nil {
+		// Case 2: Partial success (some data points were rejected)
+		requestsAccepted = 1
+		dataPointsAccepted = dataPoints - min(resp.GetPartialSuccess().GetRejectedDataPoints(), dataPoints)
+		atomic.AddInt64(&partialSuccessResponses, 1)
+		recordFailedRequest("Partial error request details: %v", req)
+	} else {
+		// Case 3: Successful response
+		requestsAccepted, dataPointsAccepted = 1, dataPoints
 	}
+	requestCounts.add(1, requestsAccepted)
+	dataPointCounts.add(dataPoints, dataPointsAccepted)
+	stats.requests.add(1, requestsAccepted)
+	stats.dataPoints.add(dataPoints, dataPointsAccepted)
+}
+
+// recordFailedRequest keeps the details of the first and last requests that failed or were
+// partially rejected.
+func recordFailedRequest(format string, req *pb.ExportMetricsServiceRequest) {
+	details := fmt.Sprintf(format, req)
+	failedRequestMutex.Lock()
+	defer failedRequestMutex.Unlock()
+	if firstFailedRequestDetails == "" {
+		firstFailedRequestDetails = details
+	}
+	lastFailedRequestDetails = details
 }
 
 func readJSONFile(filename string) (string, error) {
@@ -119,57 +120,235 @@ func readJSONFile(filename string) (string, error) {
 	return string(bytes), nil
 }
 
-func getClientCertAndPool() (tls.Certificate, *x509.CertPool) {
-	caCert, err := ioutil.ReadFile("certs/ca.crt")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	certPool := x509.NewCertPool()
-	if ok := certPool.AppendCertsFromPEM(caCert); !ok {
-		log.Fatal(err)
-	}
-
-	clientCert, err := tls.LoadX509KeyPair("certs/client.crt", "certs/client.key")
-	if err != nil {
-		log.Fatal(err)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		os.Exit(runCompare(os.Args[2:], os.Stdout, os.Stderr))
 	}
 
-	return clientCert, certPool
-}
-
-func main() {
 	filename := flag.String("filename", "", "Path to the JSON file containing the request data")
 	duration := flag.Int("duration", 0, "Duration of the load test in seconds")
 	numConcurrentRequests := flag.Int("numConcurrentRequests", 1, "Number of concurrent requests to send")
+	rate := flag.Float64("rate", 0, "Requests per second to send whatever the response time of the server, at most numConcurrentRequests at a time, so a single call in flight by default; 0 sends a new request as soon as the previous one completes")
+	reportInterval := flag.Duration("reportInterval", 0, "Interval of the interim reports written to the run log during the run, such as 1m; 0 disables them")
+	arrival := flag.String("arrival", arrivalFixed, "Arrival process of the requests with -rate: fixed or poisson")
+	generate := flag.Bool("generate", false, "Build synthetic requests following the workload profile instead of sending the request of -filename")
+	profilePath := flag.String("profile", "", "Path to a YAML workload profile of the synthetic requests, implies -generate")
+	resources := flag.Int("resources", defaultProfile.Resources, "Resources per synthetic request, overrides the profile")
+	metricsPerResource := flag.Int("metricsPerResource", defaultProfile.MetricsPerResource, "Metrics per resource of the synthetic requests, overrides the profile")
+	metricTypesFlag := flag.String("metricTypes", strings.Join(defaultProfile.MetricTypes, ","), "Comma-separated types of the synthetic metrics among "+strings.Join(metricTypes, ", ")+", overrides the profile")
+	attributeCardinality := flag.Int("attributeCardinality", defaultProfile.AttributeCardinality, "Distinct attribute sets of the synthetic data points of a metric, overrides the profile")
+	pointsPerMetric := flag.Int("pointsPerMetric", defaultProfile.PointsPerMetric, "Data points per synthetic metric, overrides the profile")
+	scenarioPath := flag.String("scenario", "", "Path to a YAML scenario of stages to run in sequence, each with a duration and a target concurrency or rate; numConcurrentRequests bounds the calls in flight of the rate stages")
+	maxErrorRate := flag.Float64("maxErrorRate", 0, "Fraction of failed calls of a stage, such as 0.05, above which the run is aborted with a non-zero exit code, overrides the scenario; 0 disables the threshold")
+	maxP99 := flag.Duration("maxP99", 0, "p99 latency of a stage, such as 50ms, above which the run is aborted with a non-zero exit code, overrides the scenario; 0 disables the threshold")
+	output := flag.String("output", "", "Path of a file to write the results of the run to, for compare or other tools")
+	outputFormatFlag := flag.String("outputFormat", "", "Format of the output file: json or csv; by default csv for a .csv file and json otherwise")
+	seed := flag.Uint64("seed", 0, "Seed of the synthetic values and of the Poisson arrivals, to reproduce a run; 0 picks a random seed")
+	coordinatorAddress := flag.String("coordinator", "", "Address to listen on for workers, such as localhost:7070, to spread the run over -workers client processes started with -worker instead of sending the requests")
+	coordinatorAllowRemote := flag.Bool("coordinatorAllowRemote", false, "Allow -coordinator to listen on an address other than a loopback one; the workers are served in plaintext without authentication, so only on a trusted network")
+	workers := flag.Int("workers", 1, "Number of workers of a run with -coordinator")
+	workerOf := flag.String("worker", "", "Address of a coordinator, such as localhost:7070, to run as one of its workers; the coordinator sends the run, so the flags besides those of the connections are ignored")
+	configPath := flag.String("config", "", "Path to a YAML file configuring the connections to the servers, overridden by the OTEL_EXPORTER_OTLP_* environment variables and the flags")
+	targets := flag.String("targets", serverAddress, "Comma-separated addresses of the servers to send the requests to")
+	connections := flag.Int("connections", 1, "Connections to open to each target, each a separate HTTP/2 connection")
+	balancing := flag.String("balancing", balancingRoundRobin, "Balancing of the calls: round_robin over every connection of every target, or pick_first over the connections of the first target with a ready connection")
+	caCert := flag.String("caCert", "certs/ca.crt", "Path to the CA certificate verifying the servers; empty uses the system roots")
+	clientCert := flag.String("clientCert", "certs/client.crt", "Path to the client certificate; empty connects without one")
+	clientKey := flag.String("clientKey", "certs/client.key", "Path to the private key of the client certificate")
+	serverName := flag.String("serverName", "", "Name to verify the server certificates against instead of the target host name")
+	insecureFlag := flag.Bool("insecure", false, "Connect in plaintext, without TLS")
+	insecureSkipVerify := flag.Bool("insecureSkipVerify", false, "Do not verify the server certificates")
+	compression := flag.String("compression", compressionNone, "Compression of the requests: none or gzip")
+	maxMessageSize := flag.Int("maxMessageSize", 0, "Maximum size in bytes of a sent or received message; 0 keeps the gRPC defaults")
+	timeout := flag.Duration("timeout", 0, "Timeout of every call, such as 5s; 0 disables it")
+	headers := make(map[string]string)
+	flag.Func("header", "Metadata header to send with every call, as key=value; may be repeated", func(s string) error {
+		parsed, err := parseHeaders(s)
+		for key, value := range parsed {
+			headers[key] = value
+		}
+		return err
+	})
 
 	flag.Parse()
 
-	if *filename == "" {
-		log.Fatal("Please provide the filename parameter")
+	conf, err := loadConnectionConfig(*configPath, os.Getenv)
+	if err != nil {
+		log.Fatalf("Failed to read the connection configuration: %v", err)
 	}
-
-	clientCert, certPool := getClientCertAndPool()
-	config := &tls.Config{
-		Certificates: []tls.Certificate{clientCert},
-		RootCAs:      certPool,
+	// The flags set on the command line override the configuration file and the environment.
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "targets":
+			conf.Targets = strings.Split(*targets, ",")
+		case "connections":
+			conf.Connections = *connections
+		case "balancing":
+			conf.Balancing = *balancing
+		case "caCert":
+			conf.CACert = *caCert
+		case "clientCert":
+			conf.ClientCert = *clientCert
+		case "clientKey":
+			conf.ClientKey = *clientKey
+		case "serverName":
+			conf.ServerName = *serverName
+		case "insecure":
+			conf.Insecure = *insecureFlag
+		case "insecureSkipVerify":
+			conf.InsecureSkipVerify = *insecureSkipVerify
+		case "compression":
+			conf.Compression = *compression
+		case "maxMessageSize":
+			conf.MaxMessageSize = *maxMessageSize
+		case "timeout":
+			conf.Timeout = *timeout
+		case "header":
+			if conf.Headers == nil {
+				conf.Headers = make(map[string]string)
+			}
+			for key, value := range headers {
+				conf.Headers[key] = value
+			}
+		}
+	})
+	if err := conf.validate(); err != nil {
+		log.Fatalf("Invalid connection configuration: %v", err)
 	}
-
-	tlsCredential := credentials.NewTLS(config)
-	conn, err := grpc.Dial(serverAddress, grpc.WithTransportCredentials(tlsCredential))
+	dialOptions, err := conf.dialOptions()
+	if err != nil {
+		log.Fatalf("Failed to load the TLS material: %v", err)
+	}
+	p, err := newPool(conf.Targets, conf.Connections, conf.Balancing, func(address string) (*grpc.ClientConn, error) {
+		return grpc.Dial(address, dialOptions...)
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to server: %v", err)
 	}
-	defer conn.Close()
+	defer p.close()
 
-	client := pb.NewMetricsServiceClient(conn)
+	if *workerOf != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := runDistributedWorker(ctx, *workerOf, p)
+		writeTargets(os.Stderr, p)
+		if err != nil {
+			log.Printf("Worker failed: %v", err)
+			stop()
+			p.close()
+			os.Exit(1)
+		}
+		return
+	}
 
-	versionClient := pv.NewVersionServiceClient(conn)
-	getVersion(versionClient)
-	requestJson, err := readJSONFile(*filename)
+	*generate = *generate || *profilePath != ""
+	if *filename == "" && !*generate {
+		log.Fatal("Please provide the filename parameter, or generate the requests with -generate or -profile")
+	}
+	if *filename != "" && *generate {
+		log.Fatal("The filename parameter cannot be used with -generate or -profile")
+	}
+	if *numConcurrentRequests < 1 {
+		log.Fatal("The numConcurrentRequests parameter must be at least 1")
+	}
+	if *rate < 0 {
+		log.Fatal("The rate parameter must not be negative")
+	}
+	if *arrival != arrivalFixed && *arrival != arrivalPoisson {
+		log.Fatalf("Unknown arrival process %q, expected %s or %s", *arrival, arrivalFixed, arrivalPoisson)
+	}
+	if *rate > 0 && *scenarioPath != "" {
+		log.Fatal("The rate parameter cannot be used with -scenario")
+	}
+	format, err := outputFormat(*output, *outputFormatFlag)
 	if err != nil {
-		log.Fatalf("Failed to read request file: %v", err)
+		log.Fatal(err)
+	}
+	if *seed == 0 {
+		*seed = rand.Uint64()
+	}
+	if *coordinatorAddress != "" && *workers < 1 {
+		log.Fatal("The workers parameter must be at least 1")
 	}
+	if *coordinatorAddress != "" && *reportInterval > 0 {
+		log.Fatal("The reportInterval parameter cannot be used with -coordinator")
+	}
+
+	// Without a scenario, the run is a single stage with the concurrency or rate of the flags.
+	stages := []stage{{Name: "run", Concurrency: *numConcurrentRequests, Ramp: rampStep}}
+	if *rate > 0 {
+		stages = []stage{{Name: "run", Rate: *rate, Ramp: rampStep}}
+	}
+	thresholds := abortThresholds{MinRequests: 100}
+	if *scenarioPath != "" {
+		sc, err := loadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("Failed to read the scenario: %v", err)
+		}
+		if err := sc.validate(); err != nil {
+			log.Fatalf("Invalid scenario: %v", err)
+		}
+		stages, thresholds = sc.Stages, sc.Abort
+	}
+	if *coordinatorAddress != "" {
+		for _, st := range stages {
+			if st.Rate == 0 && st.Concurrency < *workers {
+				log.Fatalf("The concurrency of stage %q is lower than the number of workers", st.Name)
+			}
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "maxErrorRate":
+			thresholds.MaxErrorRate = *maxErrorRate
+		case "maxP99":
+			thresholds.MaxP99 = *maxP99
+		}
+	})
+
+	var next func() *pb.ExportMetricsServiceRequest
+	var req *pb.ExportMetricsServiceRequest
+	workload := defaultProfile
+	if *generate {
+		if *profilePath != "" {
+			var err error
+			if workload, err = loadProfile(*profilePath); err != nil {
+				log.Fatalf("Failed to read the workload profile: %v", err)
+			}
+		}
+		// The flags set on the command line override the profile.
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "resources":
+				workload.Resources = *resources
+			case "metricsPerResource":
+				workload.MetricsPerResource = *metricsPerResource
+			case "metricTypes":
+				workload.MetricTypes = strings.Split(*metricTypesFlag, ",")
+			case "attributeCardinality":
+				workload.AttributeCardinality = *attributeCardinality
+			case "pointsPerMetric":
+				workload.PointsPerMetric = *pointsPerMetric
+			}
+		})
+		if err := workload.validate(); err != nil {
+			log.Fatalf("Invalid workload profile: %v", err)
+		}
+		next = newGenerator(workload, *seed).next
+	} else {
+		requestJson, err := readJSONFile(*filename)
+		if err != nil {
+			log.Fatalf("Failed to read request file: %v", err)
+		}
+		req = &pb.ExportMetricsServiceRequest{}
+		if err := jsonpb.UnmarshalString(requestJson, req); err != nil {
+			log.Fatalf("Error unmarshalling request: %v", err)
+		}
+		next = func() *pb.ExportMetricsServiceRequest { return req }
+	}
+
+	versionClient := pv.NewVersionServiceClient(p.conn())
+	getVersion(versionClient)
 
 	var outputWriter *os.File
 
@@ -180,44 +359,158 @@ func main() {
 	defer outputWriter.Close()
 	log.SetOutput(outputWriter)
 
-	var wg sync.WaitGroup
-	ctx, cancel := context.WithCancel(context.Background())
+	statusClient := pv.NewStatusServiceClient(p.conn())
+	printStatus(outputWriter, statusClient, "Server Status Before Run")
+
+	// The run ends after the duration, or on SIGTERM or SIGINT.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// A distributed run starts once every worker registered, and lasts the duration from then.
+	var coord *coordinator
+	if *coordinatorAddress != "" {
+		var planWorkload *profile
+		if *generate {
+			planWorkload = &workload
+		}
+		plan, err := newPlan(stages, *arrival, *numConcurrentRequests, *seed, req, planWorkload)
+		if err != nil {
+			log.Fatalf("Failed to build the plan of the workers: %v", err)
+		}
+		if coord, err = newCoordinator(*coordinatorAddress, *coordinatorAllowRemote, *workers, plan, stages); err != nil {
+			log.Fatalf("Failed to listen for workers: %v", err)
+		}
+		defer coord.close()
+		fmt.Fprintf(os.Stderr, "Waiting for %d workers on %s\n", *workers, coord.address())
+		if _, err := coord.waitForWorkers(ctx); err != nil {
+			log.Fatalf("The run did not start: %v", err)
+		}
+	}
 	if *duration > 0 {
-		ctx, _ = context.WithTimeout(ctx, time.Duration(*duration)*time.Second)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*duration)*time.Second)
 		defer cancel()
 	}
+	// The run is also stopped by the abort thresholds.
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-sigCh
-		cancel()
-	}()
-
-	for i := 0; i < *numConcurrentRequests; i++ {
-		wg.Add(1)
-		go sendRequests(client, requestJson, &wg, ctx, *numConcurrentRequests)
+	reporterCtx, stopReporter := context.WithCancel(ctx)
+	var reporter sync.WaitGroup
+	if *reportInterval > 0 {
+		reporter.Add(1)
+		go func() {
+			defer reporter.Done()
+			reportPeriodically(reporterCtx, *reportInterval)
+		}()
 	}
 
-	wg.Wait()
+	start := time.Now()
+	var stageStats []*stageStats
+	if coord != nil {
+		stageStats = coord.run(ctx, abort, thresholds)
+	} else {
+		r := newRunner(ctx, p, next, *numConcurrentRequests)
+		stageStats = runScenario(ctx, abort, r, stages, thresholds, *arrival, rand.New(rand.NewPCG(*seed, 1)))
+		r.wait()
+	}
+	elapsed := time.Since(start)
+	stopReporter()
+	reporter.Wait()
+	aborted := context.Cause(ctx)
+	if !errors.Is(aborted, errAborted) {
+		aborted = nil
+	}
 
 	// Print parameters used for the run
 	if outputWriter != nil {
 		fmt.Fprintf(outputWriter, "Parameters:\n")
-		fmt.Fprintf(outputWriter, "Filename: %s\n", *filename)
+		if *generate {
+			fmt.Fprintf(outputWriter, "Workload: %d resources, %d metrics per resource of types %s, %d points per metric, attribute cardinality %d\n",
+				workload.Resources, workload.MetricsPerResource, strings.Join(workload.MetricTypes, ","), workload.PointsPerMetric, workload.AttributeCardinality)
+		} else {
+			fmt.Fprintf(outputWriter, "Filename: %s\n", *filename)
+		}
+		fmt.Fprintf(outputWriter, "Seed: %d\n", *seed)
 		fmt.Fprintf(outputWriter, "Duration: %d\n", *duration)
 		fmt.Fprintf(outputWriter, "Number of Concurrent Requests: %d\n", *numConcurrentRequests)
+		fmt.Fprintf(outputWriter, "Targets: %s, %d connections each, %s balancing\n", strings.Join(conf.Targets, ", "), conf.Connections, conf.Balancing)
+		fmt.Fprintf(outputWriter, "Transport: %s\n", conf)
+		if *scenarioPath != "" {
+			fmt.Fprintf(outputWriter, "Scenario: %s\n", *scenarioPath)
+		}
+		if coord != nil {
+			fmt.Fprintf(outputWriter, "Workers: %d\n", *workers)
+		}
+		if *rate > 0 {
+			fmt.Fprintf(outputWriter, "Target Rate: %.1f requests/s (%s arrivals)\n", *rate, *arrival)
+		}
 		fmt.Fprintf(outputWriter, "GitCommitSha: %s\n", gitCommitSha)
 		buildTimeStampStr := strconv.FormatInt(buildTimeStamp, 10)
 		fmt.Fprintf(outputWriter, "BuildTimestamp: %s\n", buildTimeStampStr)
 
 		// Print summary at the end
 		// Print summary at the end
-		fmt.Fprintf(outputWriter, "Total Requests: %d\n", totalRequests)
-		fmt.Fprintf(outputWriter, "Total Successful Requests: %d\n", totalSuccessfulRequests)
-		fmt.Fprintf(outputWriter, "Total Failed Requests: %d\n", totalFailedRequests)
+		writeCounts(outputWriter, "Requests", &requestCounts)
+		writeCounts(outputWriter, "Data Points", &dataPointCounts)
+		fmt.Fprintf(outputWriter, "Partial Success Responses: %d\n", partialSuccessResponses)
+		fmt.Fprintf(outputWriter, "Requests by Status Code:\n")
+		writeStatusCodes(outputWriter, &statusCodes)
 		fmt.Fprintf(outputWriter, "First Failed Request: %s\n", firstFailedRequestDetails)
 		fmt.Fprintf(outputWriter, "Last Failed Request: %s\n", lastFailedRequestDetails)
+		fmt.Fprintf(outputWriter, "Achieved Rate: %.1f requests/s\n", float64(requestCounts.sent)/elapsed.Seconds())
+		if slices.ContainsFunc(stages, func(s stage) bool { return s.Rate > 0 }) {
+			fmt.Fprintf(outputWriter, "Late Requests: %d (sent more than %v after their intended time, or not sent or completed in time)\n", lateRequests, lateThreshold)
+			fmt.Fprintf(outputWriter, "Latency, from the intended send time in the rate stages:\n")
+		} else {
+			fmt.Fprintf(outputWriter, "Latency:\n")
+		}
+		writeLatencies(outputWriter, latencies)
+		if coord == nil {
+			// The workers of a distributed run write the statistics of their connections.
+			writeTargets(outputWriter, p)
+		}
+		if *scenarioPath != "" {
+			writeStages(outputWriter, stageStats)
+		}
+		if aborted != nil {
+			fmt.Fprintf(outputWriter, "Aborted: %v\n", aborted)
+		}
+		printStatus(outputWriter, statusClient, "Server Status After Run")
+	}
+
+	exitCode := 0
+	if aborted != nil {
+		fmt.Fprintln(os.Stderr, aborted)
+		exitCode = 1
+	}
+	if *output != "" {
+		parameters := runParameters{
+			Filename:        *filename,
+			Scenario:        *scenarioPath,
+			DurationSeconds: *duration,
+			Concurrency:     *numConcurrentRequests,
+			Rate:            *rate,
+			Arrival:         *arrival,
+			Seed:            *seed,
+			Targets:         conf.Targets,
+			Connections:     conf.Connections,
+			Balancing:       conf.Balancing,
+		}
+		if coord != nil {
+			parameters.Workers = *workers
+		}
+		if *generate {
+			parameters.Workload = &workload
+		}
+		if err := writeResultsFile(*output, format, newResults(start, parameters, elapsed, stageStats, aborted)); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write the results: %v\n", err)
+			exitCode = 1
+		}
+	}
+	if exitCode != 0 {
+		// The deferred calls do not run on exit.
+		outputWriter.Close()
+		p.close()
+		os.Exit(exitCode)
 	}
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Exit codes of the compare subcommand.
+const (
+	compareOK         = 0
+	compareRegression = 1
+	compareFailed     = 2
+)
+
+// compareTolerances are how much worse than the baseline a run may be without regressing.
+type compareTolerances struct {
+	relative  float64 // Fraction of the baseline throughput and latencies
+	errorRate float64 // Increase of the error rate, a fraction of the calls
+}
+
+// comparison is the comparison of a metric of the whole run or of a stage with the baseline.
+type comparison struct {
+	scope      string
+	metric     string
+	baseline   float64
+	current    float64
+	regression bool
+}
+
+// compareResults compares the whole run and the stages present in both runs, matched by name.
+func compareResults(baseline, current results, tolerances compareTolerances) []comparison {
+	comparisons := compareStage("total", baseline.Total, current.Total, tolerances)
+	for _, s := range current.Stages {
+		for _, base := range baseline.Stages {
+			if base.Name == s.Name {
+				comparisons = append(comparisons, compareStage("stage "+s.Name, base, s, tolerances)...)
+				break
+			}
+		}
+	}
+	return comparisons
+}
+
+func compareStage(scope string, baseline, current stageResult, tolerances compareTolerances) []comparison {
+	// The throughput regresses when it decreases, the latencies and error rate when they increase.
+	lower := func(metric string, b, c float64) comparison {
+		return comparison{scope, metric, b, c, c < b*(1-tolerances.relative)}
+	}
+	higher := func(metric string, b, c float64) comparison {
+		return comparison{scope, metric, b, c, c > b*(1+tolerances.relative)}
+	}
+	return []comparison{
+		lower("requests_per_second", baseline.RequestsPerSecond, current.RequestsPerSecond),
+		{scope, "error_rate", baseline.errorRate(), current.errorRate(), current.errorRate() > baseline.errorRate()+tolerances.errorRate},
+		higher("latency_p50_ms", baseline.Latency.P50Ms, current.Latency.P50Ms),
+		higher("latency_p90_ms", baseline.Latency.P90Ms, current.Latency.P90Ms),
+		higher("latency_p99_ms", baseline.Latency.P99Ms, current.Latency.P99Ms),
+		higher("latency_p99_9_ms", baseline.Latency.P999Ms, current.Latency.P999Ms),
+	}
+}
+
+// runCompare runs the compare subcommand, which compares the JSON results of a run with those of
+// a baseline run, and returns its exit code.
+func runCompare(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: client compare -baseline <baseline.json> [flags] <results.json>")
+		flags.PrintDefaults()
+	}
+	baselinePath := flags.String("baseline", "", "Path to the JSON results of the baseline run")
+	tolerance := flags.Float64("tolerance", 0.1, "Fraction of the baseline by which the throughput may decrease and the latencies increase")
+	errorRateTolerance := flags.Float64("errorRateTolerance", 0.01, "Increase of the error rate over the baseline allowed, as a fraction of the calls")
+	if err := flags.Parse(args); err != nil {
+		return compareFailed
+	}
+	if *baselinePath == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return compareFailed
+	}
+
+	baseline, err := readResultsFile(*baselinePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read the baseline: %v\n", err)
+		return compareFailed
+	}
+	current, err := readResultsFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read the results: %v\n", err)
+		return compareFailed
+	}
+
+	comparisons := compareResults(baseline, current, compareTolerances{relative: *tolerance, errorRate: *errorRateTolerance})
+	regressions := 0
+	writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "SCOPE\tMETRIC\tBASELINE\tCURRENT\tCHANGE\tRESULT")
+	for _, c := range comparisons {
+		change := "n/a"
+		if c.baseline != 0 {
+			change = fmt.Sprintf("%+.1f%%", (c.current-c.baseline)/c.baseline*100)
+		}
+		result := "ok"
+		if c.regression {
+			result = "REGRESSION"
+			regressions++
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%.4g\t%.4g\t%s\t%s\n", c.scope, c.metric, c.baseline, c.current, change, result)
+	}
+	writer.Flush()
+
+	if regressions > 0 {
+		fmt.Fprintf(stdout, "%d regressions\n", regressions)
+		return compareRegression
+	}
+	return compareOK
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareResults(t *testing.T) {
+	tolerances := compareTolerances{relative: 0.1, errorRate: 0.01}
+	regressions := func(current results) []string {
+		var metrics []string
+		for _, c := range compareResults(testResults(), current, tolerances) {
+			if c.regression {
+				metrics = append(metrics, c.scope+" "+c.metric)
+			}
+		}
+		return metrics
+	}
+
+	assert.Empty(t, regressions(testResults()))
+
+	within := testResults()
+	within.Total.RequestsPerSecond *= 0.95
+	within.Total.Latency.P99Ms *= 1.05
+	assert.Empty(t, regressions(within))
+
+	slower := testResults()
+	slower.Total.RequestsPerSecond *= 0.8
+	slower.Stages[0].Latency.P99Ms *= 1.5
+	slower.Stages[0].Requests.Rejected = 10
+	assert.Equal(t, []string{"total requests_per_second", "stage hold error_rate", "stage hold latency_p99_ms"}, regressions(slower))
+
+	// Stages only present in one of the runs are not compared.
+	renamed := testResults()
+	renamed.Stages[0].Name = "spike"
+	renamed.Stages[0].Latency.P99Ms *= 2
+	assert.Empty(t, regressions(renamed))
+}
+
+func TestRunCompare(t *testing.T) {
+	dir := t.TempDir()
+	baselinePath := filepath.Join(dir, "baseline.json")
+	currentPath := filepath.Join(dir, "current.json")
+	require.NoError(t, writeResultsFile(baselinePath, outputJSON, testResults()))
+	current := testResults()
+	current.Total.Latency.P50Ms *= 1.15
+	require.NoError(t, writeResultsFile(currentPath, outputJSON, current))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, compareOK, runCompare([]string{"-baseline", baselinePath, baselinePath}, &stdout, &stderr))
+
+	stdout.Reset()
+	assert.Equal(t, compareRegression, runCompare([]string{"-baseline", baselinePath, currentPath}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "REGRESSION")
+	assert.Contains(t, stdout.String(), "1 regressions")
+
+	assert.Equal(t, compareOK, runCompare([]string{"-baseline", baselinePath, "-tolerance", "0.2", currentPath}, &stdout, &stderr))
+	assert.Equal(t, compareFailed, runCompare([]string{currentPath}, &stdout, &stderr))
+	assert.Equal(t, compareFailed, runCompare([]string{"-baseline", filepath.Join(dir, "missing.json"), currentPath}, &stdout, &stderr))
+}
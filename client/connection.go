@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultOTLPPort is the port of an OTLP/gRPC endpoint URL without one.
+const defaultOTLPPort = "4317"
+
+// Compressions of the requests.
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+)
+
+// connectionConfig is how the client connects to the servers. It is read from a YAML file, then
+// from the OTLP exporter environment variables, and the flags override both.
+type connectionConfig struct {
+	Targets     []string `mapstructure:"targets"`
+	Connections int      `mapstructure:"connections"` // Connections to each target
+	Balancing   string   `mapstructure:"balancing"`
+
+	// TLS material, ignored in plaintext. Without a CA the system roots are used, and without a
+	// client certificate and key the client does not authenticate itself.
+	CACert             string `mapstructure:"ca_cert"`
+	ClientCert         string `mapstructure:"client_cert"`
+	ClientKey          string `mapstructure:"client_key"`
+	ServerName         string `mapstructure:"server_name"` // Overrides the name the server certificate is verified against
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+	Insecure           bool   `mapstructure:"insecure"` // Plaintext, without TLS
+
+	Compression    string            `mapstructure:"compression"`
+	MaxMessageSize int               `mapstructure:"max_message_size"` // Bytes of a sent or received message, 0 keeps the gRPC defaults
+	Timeout        time.Duration     `mapstructure:"timeout"`          // Of every call, 0 disables it
+	Headers        map[string]string `mapstructure:"headers"`          // Metadata sent with every call
+}
+
+// loadConnectionConfig reads the connection configuration from the YAML file at the path, if
+// any, then from the OTLP exporter environment variables read with getenv.
+func loadConnectionConfig(path string, getenv func(string) string) (connectionConfig, error) {
+	v := viper.New()
+	v.SetDefault("targets", []string{serverAddress})
+	v.SetDefault("connections", 1)
+	v.SetDefault("balancing", balancingRoundRobin)
+	v.SetDefault("ca_cert", "certs/ca.crt")
+	v.SetDefault("client_cert", "certs/client.crt")
+	v.SetDefault("client_key", "certs/client.key")
+	v.SetDefault("compression", compressionNone)
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return connectionConfig{}, err
+		}
+	}
+	var c connectionConfig
+	if err := v.Unmarshal(&c); err != nil {
+		return connectionConfig{}, err
+	}
+	if err := c.applyEnv(getenv); err != nil {
+		return connectionConfig{}, err
+	}
+	return c, nil
+}
+
+// applyEnv applies the OTEL_EXPORTER_OTLP_* environment variables, the variables specific to
+// metrics, such as OTEL_EXPORTER_OTLP_METRICS_ENDPOINT, taking precedence over the generic ones.
+func (c *connectionConfig) applyEnv(getenv func(string) string) error {
+	lookup := func(name string) (string, string) {
+		if value := getenv("OTEL_EXPORTER_OTLP_METRICS_" + name); value != "" {
+			return "OTEL_EXPORTER_OTLP_METRICS_" + name, value
+		}
+		return "OTEL_EXPORTER_OTLP_" + name, getenv("OTEL_EXPORTER_OTLP_" + name)
+	}
+
+	if name, value := lookup("ENDPOINT"); value != "" {
+		// The endpoint is an address, or a URL whose scheme tells whether to use TLS.
+		if strings.Contains(value, "://") {
+			u, err := url.Parse(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			switch u.Scheme {
+			case "http":
+				c.Insecure = true
+			case "https":
+				c.Insecure = false
+			default:
+				return fmt.Errorf("%s: unknown scheme %q, expected http or https", name, u.Scheme)
+			}
+			value = u.Host
+			if u.Port() == "" {
+				value = net.JoinHostPort(u.Hostname(), defaultOTLPPort)
+			}
+		}
+		c.Targets = []string{value}
+	}
+	if name, value := lookup("INSECURE"); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		c.Insecure = insecure
+	}
+	if _, value := lookup("CERTIFICATE"); value != "" {
+		c.CACert = value
+	}
+	if _, value := lookup("CLIENT_CERTIFICATE"); value != "" {
+		c.ClientCert = value
+	}
+	if _, value := lookup("CLIENT_KEY"); value != "" {
+		c.ClientKey = value
+	}
+	if _, value := lookup("COMPRESSION"); value != "" {
+		c.Compression = value
+	}
+	if name, value := lookup("TIMEOUT"); value != "" {
+		milliseconds, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: the timeout must be a number of milliseconds: %w", name, err)
+		}
+		c.Timeout = time.Duration(milliseconds) * time.Millisecond
+	}
+	if name, value := lookup("HEADERS"); value != "" {
+		headers, err := parseHeaders(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if c.Headers == nil {
+			c.Headers = make(map[string]string)
+		}
+		for key, value := range headers {
+			c.Headers[key] = value
+		}
+	}
+	return nil
+}
+
+// parseHeaders parses a comma-separated list of key=value headers, whose values are URL-encoded,
+// as in OTEL_EXPORTER_OTLP_HEADERS.
+func parseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, header := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(header, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", header)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of header %q: %w", key, err)
+		}
+		headers[strings.ToLower(key)] = value
+	}
+	return headers, nil
+}
+
+func (c connectionConfig) validate() error {
+	switch {
+	case len(c.Targets) == 0:
+		return fmt.Errorf("at least one target is required")
+	case c.Connections < 1:
+		return fmt.Errorf("the number of connections must be at least 1")
+	case c.Balancing != balancingRoundRobin && c.Balancing != balancingPickFirst:
+		return fmt.Errorf("unknown balancing %q, expected %s or %s", c.Balancing, balancingRoundRobin, balancingPickFirst)
+	case c.Compression != compressionNone && c.Compression != compressionGzip:
+		return fmt.Errorf("unknown compression %q, expected %s or %s", c.Compression, compressionNone, compressionGzip)
+	case c.MaxMessageSize < 0:
+		return fmt.Errorf("the maximum message size must not be negative")
+	case c.Timeout < 0:
+		return fmt.Errorf("the timeout must not be negative")
+	case !c.Insecure && c.ClientCert != "" && c.ClientKey == "":
+		return fmt.Errorf("the client key is required with a client certificate")
+	}
+	for _, target := range c.Targets {
+		if target == "" {
+			return fmt.Errorf("the targets must not be empty")
+		}
+	}
+	return nil
+}
+
+// String describes the transport, for the summary.
+func (c connectionConfig) String() string {
+	security := "plaintext"
+	if !c.Insecure {
+		security = "TLS"
+		if c.ClientCert != "" {
+			security = "mTLS"
+		}
+		if c.InsecureSkipVerify {
+			security += " without verification"
+		}
+	}
+	fields := []string{security, c.Compression + " compression"}
+	if c.MaxMessageSize > 0 {
+		fields = append(fields, fmt.Sprintf("max message size %d bytes", c.MaxMessageSize))
+	}
+	if c.Timeout > 0 {
+		fields = append(fields, fmt.Sprintf("timeout %v", c.Timeout))
+	}
+	if len(c.Headers) > 0 {
+		fields = append(fields, fmt.Sprintf("%d headers", len(c.Headers)))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// tlsConfig loads the TLS material.
+func (c connectionConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{ServerName: c.ServerName, InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CACert != "" {
+		caCert, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in %s", c.CACert)
+		}
+	}
+	if c.ClientCert != "" {
+		clientCert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{clientCert}
+	}
+	return config, nil
+}
+
+// dialOptions returns the options of the connections to the targets.
+func (c connectionConfig) dialOptions() ([]grpc.DialOption, error) {
+	credential := insecure.NewCredentials()
+	if !c.Insecure {
+		config, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		credential = credentials.NewTLS(config)
+	}
+
+	var callOptions []grpc.CallOption
+	if c.Compression == compressionGzip {
+		callOptions = append(callOptions, grpc.UseCompressor(gzip.Name))
+	}
+	if c.MaxMessageSize > 0 {
+		callOptions = append(callOptions, grpc.MaxCallSendMsgSize(c.MaxMessageSize), grpc.MaxCallRecvMsgSize(c.MaxMessageSize))
+	}
+
+	var headers []string
+	for key, value := range c.Headers {
+		headers = append(headers, key, value)
+	}
+	timeout := c.Timeout
+	interceptor := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if len(headers) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, headers...)
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(credential),
+		grpc.WithDefaultCallOptions(callOptions...),
+		grpc.WithUnaryInterceptor(interceptor),
+	}, nil
+}
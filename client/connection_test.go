@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConnectionConfig(t *testing.T) {
+	c, err := loadConnectionConfig("", func(string) string { return "" })
+	require.NoError(t, err)
+	assert.Equal(t, connectionConfig{
+		Targets:     []string{serverAddress},
+		Connections: 1,
+		Balancing:   balancingRoundRobin,
+		CACert:      "certs/ca.crt",
+		ClientCert:  "certs/client.crt",
+		ClientKey:   "certs/client.key",
+		Compression: compressionNone,
+	}, c)
+	assert.NoError(t, c.validate())
+
+	path := filepath.Join(t.TempDir(), "connection.yaml")
+	content := `
+targets: [staging-1:4317, staging-2:4317]
+connections: 4
+client_cert: ""
+client_key: ""
+server_name: metrics.staging
+compression: gzip
+max_message_size: 16777216
+timeout: 5s
+headers:
+  tenant: team-a
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	c, err = loadConnectionConfig(path, func(string) string { return "" })
+	require.NoError(t, err)
+	assert.Equal(t, connectionConfig{
+		Targets:        []string{"staging-1:4317", "staging-2:4317"},
+		Connections:    4,
+		Balancing:      balancingRoundRobin,
+		CACert:         "certs/ca.crt",
+		ServerName:     "metrics.staging",
+		Compression:    compressionGzip,
+		MaxMessageSize: 16 << 20,
+		Timeout:        5 * time.Second,
+		Headers:        map[string]string{"tenant": "team-a"},
+	}, c)
+	assert.NoError(t, c.validate())
+
+	// The environment overrides the file, and the variables of metrics the generic ones.
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT":         "https://collector:4317",
+		"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT": "http://metrics:4317",
+		"OTEL_EXPORTER_OTLP_CERTIFICATE":      "/etc/ca.pem",
+		"OTEL_EXPORTER_OTLP_COMPRESSION":      "none",
+		"OTEL_EXPORTER_OTLP_TIMEOUT":          "2500",
+		"OTEL_EXPORTER_OTLP_HEADERS":          "Authorization=Bearer%20token, tenant=team-b",
+	}
+	c, err = loadConnectionConfig(path, func(name string) string { return env[name] })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metrics:4317"}, c.Targets)
+	assert.True(t, c.Insecure)
+	assert.Equal(t, "/etc/ca.pem", c.CACert)
+	assert.Equal(t, compressionNone, c.Compression)
+	assert.Equal(t, 2500*time.Millisecond, c.Timeout)
+	assert.Equal(t, map[string]string{"authorization": "Bearer token", "tenant": "team-b"}, c.Headers)
+
+	// An endpoint URL without a port has the default port of OTLP/gRPC.
+	tests := []struct {
+		endpoint, want string
+	}{
+		{"https://collector", "collector:4317"},
+		{"http://[::1]", "[::1]:4317"},
+		{"https://collector:443", "collector:443"},
+		{"collector:4318", "collector:4318"},
+	}
+	for _, tt := range tests {
+		c, err = loadConnectionConfig("", func(name string) string {
+			if name == "OTEL_EXPORTER_OTLP_ENDPOINT" {
+				return tt.endpoint
+			}
+			return ""
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{tt.want}, c.Targets, tt.endpoint)
+	}
+
+	c, err = loadConnectionConfig("connections/example.yaml", func(string) string { return "" })
+	require.NoError(t, err)
+	assert.NoError(t, c.validate())
+}
+
+func TestConnectionConfig_ApplyEnv_Invalid(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{"OTEL_EXPORTER_OTLP_ENDPOINT", "grpc://collector:4317"},
+		{"OTEL_EXPORTER_OTLP_INSECURE", "maybe"},
+		{"OTEL_EXPORTER_OTLP_TIMEOUT", "5s"},
+		{"OTEL_EXPORTER_OTLP_HEADERS", "tenant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c connectionConfig
+			err := c.applyEnv(func(name string) string {
+				if name == tt.name {
+					return tt.value
+				}
+				return ""
+			})
+			assert.ErrorContains(t, err, tt.name)
+		})
+	}
+}
+
+func TestConnectionConfig_Validate(t *testing.T) {
+	valid, err := loadConnectionConfig("", func(string) string { return "" })
+	require.NoError(t, err)
+	tests := []struct {
+		name   string
+		modify func(c *connectionConfig)
+	}{
+		{"no targets", func(c *connectionConfig) { c.Targets = nil }},
+		{"empty target", func(c *connectionConfig) { c.Targets = []string{"a:1", ""} }},
+		{"no connections", func(c *connectionConfig) { c.Connections = 0 }},
+		{"unknown balancing", func(c *connectionConfig) { c.Balancing = "random" }},
+		{"unknown compression", func(c *connectionConfig) { c.Compression = "zstd" }},
+		{"negative max message size", func(c *connectionConfig) { c.MaxMessageSize = -1 }},
+		{"negative timeout", func(c *connectionConfig) { c.Timeout = -time.Second }},
+		{"client certificate without key", func(c *connectionConfig) { c.ClientKey = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			assert.Error(t, c.validate())
+		})
+	}
+}
+
+func TestConnectionConfig_TLSConfig(t *testing.T) {
+	c := connectionConfig{CACert: "../certs/ca.crt", ClientCert: "../certs/client.crt", ClientKey: "../certs/client.key", ServerName: "metrics.staging"}
+	config, err := c.tlsConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+	assert.Equal(t, "metrics.staging", config.ServerName)
+
+	c.CACert = "../certs/client.key"
+	_, err = c.tlsConfig()
+	assert.Error(t, err)
+	c.CACert, c.ClientCert = "", "missing.crt"
+	_, err = c.tlsConfig()
+	assert.Error(t, err)
+}
+
+// recordingMetricsServer records the metadata of the Export calls, and answers them after a delay.
+type recordingMetricsServer struct {
+	pb.UnimplementedMetricsServiceServer
+	delay    time.Duration
+	metadata chan metadata.MD
+}
+
+func (s *recordingMetricsServer) Export(ctx context.Context, _ *pb.ExportMetricsServiceRequest) (*pb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.metadata <- md
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return &pb.ExportMetricsServiceResponse{}, nil
+}
+
+// compressionRecorder records the compression of the calls received by a server.
+type compressionRecorder struct {
+	compressions chan string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		r.compressions <- header.Compression
+	}
+}
+
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestConnectionConfig_DialOptions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	compressions := &compressionRecorder{compressions: make(chan string, 2)}
+	server := grpc.NewServer(grpc.StatsHandler(compressions))
+	metrics := &recordingMetricsServer{metadata: make(chan metadata.MD, 2)}
+	pb.RegisterMetricsServiceServer(server, metrics)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	c := connectionConfig{
+		Insecure:       true,
+		Compression:    compressionGzip,
+		MaxMessageSize: 1 << 20,
+		Timeout:        100 * time.Millisecond,
+		Headers:        map[string]string{"tenant": "team-a"},
+	}
+	options, err := c.dialOptions()
+	require.NoError(t, err)
+	conn, err := grpc.Dial(listener.Addr().String(), options...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewMetricsServiceClient(conn)
+
+	_, err = client.Export(context.Background(), emptyRequest())
+	require.NoError(t, err)
+	md := <-metrics.metadata
+	assert.Equal(t, []string{"team-a"}, md.Get("tenant"))
+	assert.Equal(t, "gzip", <-compressions.compressions)
+
+	// The calls time out.
+	metrics.delay = time.Second
+	_, err = client.Export(context.Background(), emptyRequest())
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
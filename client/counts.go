@@ -0,0 +1,58 @@
+package main
+
+import (
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"sync/atomic"
+)
+
+// counts counts requests or data points by outcome. It is safe for concurrent use.
+type counts struct {
+	sent     int64
+	accepted int64
+	rejected int64
+}
+
+// add counts sent items, of which accepted were accepted and the others rejected.
+func (c *counts) add(sent, accepted int64) {
+	atomic.AddInt64(&c.sent, sent)
+	atomic.AddInt64(&c.accepted, accepted)
+	atomic.AddInt64(&c.rejected, sent-accepted)
+}
+
+// load returns the counts so far.
+func (c *counts) load() (sent, accepted, rejected int64) {
+	return atomic.LoadInt64(&c.sent), atomic.LoadInt64(&c.accepted), atomic.LoadInt64(&c.rejected)
+}
+
+// countDataPoints returns the number of data points of an export request, whatever the types of
+// its metrics.
+func countDataPoints(req *pb.ExportMetricsServiceRequest) int64 {
+	var total int64
+	for _, resourceMetrics := range req.GetResourceMetrics() {
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			for _, metric := range scopeMetrics.GetMetrics() {
+				total += int64(metricDataPoints(metric))
+			}
+		}
+	}
+	return total
+}
+
+// metricDataPoints returns the number of data points a metric carries.
+func metricDataPoints(metric *v1.Metric) int {
+	switch data := metric.Data.(type) {
+	case *v1.Metric_Gauge:
+		return len(data.Gauge.GetDataPoints())
+	case *v1.Metric_Sum:
+		return len(data.Sum.GetDataPoints())
+	case *v1.Metric_Histogram:
+		return len(data.Histogram.GetDataPoints())
+	case *v1.Metric_ExponentialHistogram:
+		return len(data.ExponentialHistogram.GetDataPoints())
+	case *v1.Metric_Summary:
+		return len(data.Summary.GetDataPoints())
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"testing"
+)
+
+func TestCountDataPoints(t *testing.T) {
+	metrics := []*v1.Metric{
+		{Data: &v1.Metric_Gauge{Gauge: &v1.Gauge{DataPoints: make([]*v1.NumberDataPoint, 2)}}},
+		{Data: &v1.Metric_Sum{Sum: &v1.Sum{DataPoints: make([]*v1.NumberDataPoint, 3)}}},
+		{Data: &v1.Metric_Histogram{Histogram: &v1.Histogram{DataPoints: make([]*v1.HistogramDataPoint, 1)}}},
+		{Data: &v1.Metric_ExponentialHistogram{ExponentialHistogram: &v1.ExponentialHistogram{DataPoints: make([]*v1.ExponentialHistogramDataPoint, 4)}}},
+		{Data: &v1.Metric_Summary{Summary: &v1.Summary{DataPoints: make([]*v1.SummaryDataPoint, 5)}}},
+		{Name: "no data"},
+	}
+	req := &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{
+			{ScopeMetrics: []*v1.ScopeMetrics{{Metrics: metrics[:2]}, {Metrics: metrics[2:4]}}},
+			{ScopeMetrics: []*v1.ScopeMetrics{{Metrics: metrics[4:]}}},
+		},
+	}
+
+	assert.Equal(t, int64(15), countDataPoints(req))
+	assert.Equal(t, int64(0), countDataPoints(&pb.ExportMetricsServiceRequest{}))
+}
+
+func TestCounts(t *testing.T) {
+	var c counts
+	c.add(10, 10)
+	c.add(5, 2)
+	c.add(1, 0)
+
+	sent, accepted, rejected := c.load()
+	assert.Equal(t, int64(16), sent)
+	assert.Equal(t, int64(12), accepted)
+	assert.Equal(t, int64(4), rejected)
+}
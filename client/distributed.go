@@ -0,0 +1,629 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log"
+	"math/rand/v2"
+	"metrics/client/pb/pl"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// startDelay is how long after the last worker registers the workers start, for the plans to
+	// reach every worker first.
+	startDelay = 2 * time.Second
+	// workerStatsInterval is how often the workers send their statistics to the coordinator.
+	workerStatsInterval = time.Second
+	// stopGracePeriod is how long the coordinator waits for the final statistics of the workers
+	// once it stopped them.
+	stopGracePeriod = 10 * time.Second
+)
+
+// errStopped is the cause of the end of the run of a worker stopped by the coordinator.
+var errStopped = errors.New("stopped by the coordinator")
+
+// coordinator serves CoordinatorService: it waits for the workers of a run, sends each of them its
+// share of the run and merges their statistics. The workers connect in plaintext, so they must run
+// on the same box or on a trusted network.
+type coordinator struct {
+	pl.UnimplementedCoordinatorServiceServer
+
+	plan     *pl.Plan // Plan of the whole run, split between the workers
+	stages   []stage
+	workers  int
+	listener net.Listener
+	server   *grpc.Server
+
+	mutex      sync.Mutex
+	names      []string          // Names of the registered workers by index, empty for a free slot
+	registered int               // Workers in names
+	stats      []*pl.WorkerStats // Latest statistics of each worker
+	finished   int               // Workers whose run is over, or which left
+	stopReason string
+
+	ready    chan struct{} // Closed once every worker registered
+	stopping chan struct{} // Closed to stop the workers
+	stopOnce sync.Once
+	done     chan struct{} // Closed once every worker finished
+}
+
+// newCoordinator listens for the workers of a plan on the address and serves them. As the workers
+// are served in plaintext without authentication, the address must be a loopback address unless
+// allowRemote is set.
+func newCoordinator(address string, allowRemote bool, workers int, plan *pl.Plan, stages []stage) (*coordinator, error) {
+	if !allowRemote && !isLoopbackAddress(address) {
+		return nil, fmt.Errorf("the coordinator can only listen on a loopback address, got %q", address)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	c := &coordinator{
+		plan:     plan,
+		stages:   stages,
+		workers:  workers,
+		listener: listener,
+		server:   grpc.NewServer(),
+		names:    make([]string, workers),
+		stats:    make([]*pl.WorkerStats, workers),
+		ready:    make(chan struct{}),
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	pl.RegisterCoordinatorServiceServer(c.server, c)
+	go c.server.Serve(listener)
+	return c, nil
+}
+
+// isLoopbackAddress reports whether the TCP address is on a loopback interface, which cannot be
+// reached from other hosts.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// address returns the address the coordinator listens on.
+func (c *coordinator) address() string {
+	return c.listener.Addr().String()
+}
+
+// close stops the server, ending the streams of the workers still connected.
+func (c *coordinator) close() {
+	c.server.Stop()
+}
+
+// Join registers a worker, sends it its plan once every worker registered, and records its
+// statistics until its run is over.
+func (c *coordinator) Join(stream pl.CoordinatorService_JoinServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if msg.GetRegister().GetName() == "" {
+		return status.Error(codes.InvalidArgument, "the first message must be a Register with a name")
+	}
+	index, err := c.register(msg.GetRegister().GetName())
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-c.ready:
+	case <-c.stopping:
+		c.finish()
+		return status.Error(codes.Aborted, "the run was stopped before it started")
+	case <-stream.Context().Done():
+		c.leave(index)
+		return stream.Context().Err()
+	}
+	defer c.finish()
+	if err := stream.Send(&pl.CoordinatorMessage{Message: &pl.CoordinatorMessage_Plan{Plan: c.workerPlan(index)}}); err != nil {
+		return err
+	}
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				received <- err
+				return
+			}
+			if stats := msg.GetStats(); stats != nil {
+				if err := c.update(index, stats); err != nil {
+					received <- err
+					return
+				}
+				if stats.GetFinal() {
+					received <- nil
+					return
+				}
+			}
+		}
+	}()
+	select {
+	case err := <-received:
+		return c.left(index, err)
+	case <-c.stopping:
+	}
+	c.mutex.Lock()
+	reason := c.stopReason
+	c.mutex.Unlock()
+	if err := stream.Send(&pl.CoordinatorMessage{Message: &pl.CoordinatorMessage_Stop{Stop: &pl.Stop{Reason: reason}}}); err != nil {
+		return c.left(index, err)
+	}
+	return c.left(index, <-received)
+}
+
+// register adds a worker to the run and returns its index. The start time of the run is set once
+// every worker registered.
+func (c *coordinator) register(name string) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.registered == c.workers {
+		return 0, status.Errorf(codes.ResourceExhausted, "the %d workers of the run have already registered", c.workers)
+	}
+	index := slices.Index(c.names, "")
+	c.names[index] = name
+	c.registered++
+	log.Printf("Worker %s registered, %d of %d", name, c.registered, c.workers)
+	if c.registered == c.workers {
+		c.plan.StartTime = timestamppb.New(time.Now().Add(startDelay))
+		close(c.ready)
+	}
+	return index, nil
+}
+
+// leave frees the slot of a worker whose stream ended before the start of the run, for another
+// worker to take. Once every worker registered, the share of the run of each worker is set, so
+// the run is stopped instead.
+func (c *coordinator) leave(index int) {
+	c.mutex.Lock()
+	name := c.names[index]
+	if c.registered < c.workers {
+		c.names[index] = ""
+		c.registered--
+		log.Printf("Worker %s left before the start of the run, %d of %d registered", name, c.registered, c.workers)
+		c.mutex.Unlock()
+		return
+	}
+	c.mutex.Unlock()
+	c.finish()
+	c.stop(fmt.Sprintf("worker %s left before the start of the run", name))
+}
+
+func (c *coordinator) name(index int) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.names[index]
+}
+
+// workerPlan returns the plan of a worker.
+func (c *coordinator) workerPlan(index int) *pl.Plan {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return splitPlan(c.plan, index, c.workers)
+}
+
+// update records the latest statistics of a worker, unless they are invalid.
+func (c *coordinator) update(index int, stats *pl.WorkerStats) error {
+	for _, s := range stats.GetStages() {
+		if err := checkHistogram(s.GetLatencies()); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid latencies of stage %d: %v", s.GetIndex(), err)
+		}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stats[index] = stats
+	return nil
+}
+
+// left logs a worker whose stream ended before it sent its final statistics. The statistics it
+// sent before are kept. A worker leaving before the start of the run stops the run, which would
+// otherwise miss its share.
+func (c *coordinator) left(index int, err error) error {
+	if err == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	started := !time.Now().Before(c.plan.GetStartTime().AsTime())
+	c.mutex.Unlock()
+	if !started {
+		c.stop(fmt.Sprintf("worker %s left before the start of the run", c.name(index)))
+		return err
+	}
+	log.Printf("Worker %s left before the end of its run: %v", c.name(index), err)
+	return err
+}
+
+func (c *coordinator) finish() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.finished++
+	if c.finished == c.workers {
+		close(c.done)
+	}
+}
+
+// stop tells the workers to end their run.
+func (c *coordinator) stop(reason string) {
+	c.stopOnce.Do(func() {
+		c.mutex.Lock()
+		c.stopReason = reason
+		c.mutex.Unlock()
+		close(c.stopping)
+	})
+}
+
+// waitForWorkers waits for every worker to register, then for the start of the run, which it
+// returns. It fails if a worker leaves before the start.
+func (c *coordinator) waitForWorkers(ctx context.Context) (time.Time, error) {
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		c.stop(context.Cause(ctx).Error())
+		return time.Time{}, context.Cause(ctx)
+	}
+	c.mutex.Lock()
+	start := c.plan.GetStartTime().AsTime()
+	c.mutex.Unlock()
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return start, nil
+	case <-c.stopping:
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return time.Time{}, errors.New(c.stopReason)
+	case <-ctx.Done():
+		c.stop(context.Cause(ctx).Error())
+		return time.Time{}, context.Cause(ctx)
+	}
+}
+
+// run waits until every worker finished its run or the context of the run is done, stopping the
+// run with errAborted as soon as the merged calls of the current stage exceed the abort
+// thresholds. It then stops the workers, sets the totals of the run from their final statistics
+// and returns the statistics of the stages that ran.
+func (c *coordinator) run(ctx context.Context, stop context.CancelCauseFunc, abort abortThresholds) []*stageStats {
+	ticker := time.NewTicker(abortCheckInterval)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-c.done:
+			break loop
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			if stages := c.merge(); len(stages) > 0 {
+				if err := abort.check(stages[len(stages)-1]); err != nil {
+					stop(err)
+				}
+			}
+		}
+	}
+
+	reason := "the run is over"
+	if ctx.Err() != nil {
+		reason = context.Cause(ctx).Error()
+	}
+	c.stop(reason)
+	select {
+	case <-c.done:
+	case <-time.After(stopGracePeriod):
+		log.Printf("Some workers did not send their final statistics within %v", stopGracePeriod)
+	}
+
+	stages := c.merge()
+	for _, stats := range stages {
+		// The calls of a stage shorter than the check interval are checked too.
+		if err := abort.check(stats); err != nil {
+			stop(err)
+		}
+	}
+	c.mergeTotals(stages)
+	return stages
+}
+
+// merge returns the statistics of the stages the workers started, summed over the workers.
+func (c *coordinator) merge() []*stageStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return mergeWorkerStats(c.stages, c.stats)
+}
+
+// mergeTotals sets the totals of the run from the statistics of its stages and of the workers.
+func (c *coordinator) mergeTotals(stages []*stageStats) {
+	for _, stats := range stages {
+		sent, accepted, _ := stats.requests.load()
+		requestCounts.add(sent, accepted)
+		sent, accepted, _ = stats.dataPoints.load()
+		dataPointCounts.add(sent, accepted)
+		latencies.merge(stats.latencies.toProto())
+		lateRequests += stats.late
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, ws := range c.stats {
+		for code, n := range ws.GetStatusCodes() {
+			statusCodes.add(codes.Code(code), n)
+		}
+		partialSuccessResponses += ws.GetPartialSuccessResponses()
+		if firstFailedRequestDetails == "" {
+			firstFailedRequestDetails = ws.GetFirstFailedRequest()
+		}
+		if ws.GetLastFailedRequest() != "" {
+			lastFailedRequestDetails = ws.GetLastFailedRequest()
+		}
+	}
+}
+
+// mergeWorkerStats sums the statistics of the stages of the workers. A stage ran for as long as
+// it ran on the slowest worker.
+func mergeWorkerStats(stages []stage, workers []*pl.WorkerStats) []*stageStats {
+	var merged []*stageStats
+	for _, ws := range workers {
+		for _, s := range ws.GetStages() {
+			index := int(s.GetIndex())
+			if index < 0 || index >= len(stages) {
+				continue
+			}
+			for len(merged) <= index {
+				stats := &stageStats{stage: stages[len(merged)], latencies: newHistogram()}
+				stats.elapsed.Store(1)
+				merged = append(merged, stats)
+			}
+			stats := merged[index]
+			stats.requests.add(s.GetRequests().GetSent(), s.GetRequests().GetAccepted())
+			stats.dataPoints.add(s.GetDataPoints().GetSent(), s.GetDataPoints().GetAccepted())
+			stats.late += s.GetLateRequests()
+			stats.latencies.merge(s.GetLatencies())
+			stats.elapsed.Store(max(stats.elapsed.Load(), int64(s.GetElapsed().AsDuration())))
+		}
+	}
+	return merged
+}
+
+// newPlan returns the plan of a whole distributed run, sending either the request or synthetic
+// requests following the workload profile.
+func newPlan(stages []stage, arrival string, maxInFlight int, seed uint64, req *pb.ExportMetricsServiceRequest, workload *profile) (*pl.Plan, error) {
+	plan := &pl.Plan{
+		Arrival:       arrival,
+		MaxInFlight:   int32(maxInFlight),
+		Seed:          seed,
+		StatsInterval: durationpb.New(workerStatsInterval),
+	}
+	for _, st := range stages {
+		plan.Stages = append(plan.Stages, &pl.Stage{
+			Name:        st.Name,
+			Duration:    durationpb.New(st.Duration),
+			Concurrency: int32(st.Concurrency),
+			Rate:        st.Rate,
+			Ramp:        st.Ramp,
+		})
+	}
+	if workload != nil {
+		plan.Workload = &pl.Plan_Profile{Profile: &pl.Profile{
+			Resources:            int32(workload.Resources),
+			MetricsPerResource:   int32(workload.MetricsPerResource),
+			MetricTypes:          workload.MetricTypes,
+			AttributeCardinality: int32(workload.AttributeCardinality),
+			PointsPerMetric:      int32(workload.PointsPerMetric),
+		}}
+		return plan, nil
+	}
+	request, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	plan.Workload = &pl.Plan_Request{Request: request}
+	return plan, nil
+}
+
+// splitPlan returns the share of a worker of a plan. The concurrency of the stages is spread over
+// the workers, the first ones running one more closed-loop worker when it is not a multiple of
+// their number, and the rates and the calls in flight are divided evenly.
+func splitPlan(plan *pl.Plan, index, count int) *pl.Plan {
+	p := proto.Clone(plan).(*pl.Plan)
+	p.WorkerIndex, p.WorkerCount = int32(index), int32(count)
+	p.MaxInFlight = max(plan.GetMaxInFlight()/int32(count), 1)
+	p.Seed = plan.GetSeed() + uint64(index)
+	for _, st := range p.Stages {
+		concurrency := st.Concurrency / int32(count)
+		if int32(index) < st.Concurrency%int32(count) {
+			concurrency++
+		}
+		st.Concurrency = concurrency
+		st.Rate /= float64(count)
+	}
+	return p
+}
+
+// planStages returns the stages of a plan.
+func planStages(plan *pl.Plan) []stage {
+	var stages []stage
+	for _, st := range plan.GetStages() {
+		stages = append(stages, stage{
+			Name:        st.GetName(),
+			Duration:    st.GetDuration().AsDuration(),
+			Concurrency: int(st.GetConcurrency()),
+			Rate:        st.GetRate(),
+			Ramp:        st.GetRamp(),
+		})
+	}
+	return stages
+}
+
+// planWorkload returns the function returning the requests to send of a plan.
+func planWorkload(plan *pl.Plan) (func() *pb.ExportMetricsServiceRequest, error) {
+	switch workload := plan.GetWorkload().(type) {
+	case *pl.Plan_Request:
+		req := &pb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(workload.Request, req); err != nil {
+			return nil, fmt.Errorf("invalid request in the plan: %w", err)
+		}
+		return func() *pb.ExportMetricsServiceRequest { return req }, nil
+	case *pl.Plan_Profile:
+		p := profile{
+			Resources:            int(workload.Profile.GetResources()),
+			MetricsPerResource:   int(workload.Profile.GetMetricsPerResource()),
+			MetricTypes:          workload.Profile.GetMetricTypes(),
+			AttributeCardinality: int(workload.Profile.GetAttributeCardinality()),
+			PointsPerMetric:      int(workload.Profile.GetPointsPerMetric()),
+		}
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("invalid workload profile in the plan: %w", err)
+		}
+		return newGenerator(p, plan.GetSeed()).next, nil
+	}
+	return nil, errors.New("the plan has no workload")
+}
+
+// runDistributedWorker joins the coordinator at the address, runs the share of the run the
+// coordinator sends with the client, and sends its statistics to the coordinator until its run is
+// over. The run ends early when the coordinator stops it, goes away, or the context is done.
+func runDistributedWorker(ctx context.Context, address string, client pb.MetricsServiceClient) error {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	streamCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := pl.NewCoordinatorServiceClient(conn).Join(streamCtx)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	register := &pl.Register{Name: fmt.Sprintf("%s-%d", hostname, os.Getpid())}
+	if err := stream.Send(&pl.WorkerMessage{Message: &pl.WorkerMessage_Register{Register: register}}); err != nil {
+		return err
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to receive the plan: %w", err)
+	}
+	plan := msg.GetPlan()
+	if plan == nil {
+		return errors.New("the coordinator did not send a plan")
+	}
+	next, err := planWorkload(plan)
+	if err != nil {
+		return err
+	}
+	start := plan.GetStartTime().AsTime()
+	log.Printf("Worker %d of %d, starting at %v", plan.GetWorkerIndex()+1, plan.GetWorkerCount(), start)
+
+	runCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				stop(err)
+				return
+			}
+			if s := msg.GetStop(); s != nil {
+				log.Printf("Stopped by the coordinator: %s", s.GetReason())
+				stop(errStopped)
+			}
+		}
+	}()
+
+	r := newRunner(runCtx, client, next, int(plan.GetMaxInFlight()))
+	if sleepUntil(runCtx, start) {
+		sending := make(chan struct{})
+		sent := make(chan struct{})
+		go func() {
+			defer close(sent)
+			sendStatsPeriodically(sending, stream, r, plan.GetStatsInterval().AsDuration())
+		}()
+		// The coordinator checks the abort thresholds on the merged statistics.
+		runScenario(runCtx, stop, r, planStages(plan), abortThresholds{}, plan.GetArrival(), rand.New(rand.NewPCG(plan.GetSeed(), 1)))
+		r.wait()
+		close(sending)
+		<-sent
+	}
+
+	if err := stream.Send(workerMessage(r, true)); err != nil {
+		return fmt.Errorf("failed to send the final statistics: %w", err)
+	}
+	stream.CloseSend()
+	// The coordinator ends the stream once it received the final statistics.
+	<-received
+	return nil
+}
+
+// sendStatsPeriodically sends the statistics of the worker every interval until done is closed.
+// A failed send is ignored, the receiving side of the stream notices the coordinator went away.
+func sendStatsPeriodically(done <-chan struct{}, stream pl.CoordinatorService_JoinClient, r *runner, interval time.Duration) {
+	ticker := time.NewTicker(max(interval, 100*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			stream.Send(workerMessage(r, false))
+		}
+	}
+}
+
+// workerMessage returns the statistics of the worker since the start of its run.
+func workerMessage(r *runner, final bool) *pl.WorkerMessage {
+	ws := &pl.WorkerStats{
+		StatusCodes:             make(map[uint32]int64),
+		PartialSuccessResponses: atomic.LoadInt64(&partialSuccessResponses),
+		Final:                   final,
+	}
+	for _, code := range statusCodes.sorted() {
+		ws.StatusCodes[uint32(code)] = statusCodes.get(code)
+	}
+	failedRequestMutex.Lock()
+	ws.FirstFailedRequest, ws.LastFailedRequest = firstFailedRequestDetails, lastFailedRequestDetails
+	failedRequestMutex.Unlock()
+	for i, stats := range r.startedStages() {
+		ws.Stages = append(ws.Stages, &pl.StageStats{
+			Index:        int32(i),
+			Elapsed:      durationpb.New(stats.duration()),
+			Requests:     countsToProto(&stats.requests),
+			DataPoints:   countsToProto(&stats.dataPoints),
+			LateRequests: atomic.LoadInt64(&stats.late),
+			Latencies:    stats.latencies.toProto(),
+		})
+	}
+	return &pl.WorkerMessage{Message: &pl.WorkerMessage_Stats{Stats: ws}}
+}
+
+func countsToProto(c *counts) *pl.Counts {
+	sent, accepted, rejected := c.load()
+	return &pl.Counts{Sent: sent, Accepted: accepted, Rejected: rejected}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"math"
+	"metrics/client/pb/pl"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitPlan(t *testing.T) {
+	plan, err := newPlan([]stage{
+		{Name: "closed", Duration: time.Second, Concurrency: 5, Ramp: rampStep},
+		{Name: "open", Duration: time.Second, Rate: 300, Ramp: rampLinear},
+	}, arrivalPoisson, 10, 42, emptyRequest(), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		index       int
+		concurrency int
+		maxInFlight int32
+	}{
+		{0, 2, 3},
+		{1, 2, 3},
+		{2, 1, 3},
+	}
+	for _, tt := range tests {
+		p := splitPlan(plan, tt.index, 3)
+		assert.Equal(t, int32(tt.index), p.GetWorkerIndex())
+		assert.Equal(t, int32(3), p.GetWorkerCount())
+		assert.Equal(t, tt.maxInFlight, p.GetMaxInFlight())
+		assert.Equal(t, uint64(42+tt.index), p.GetSeed())
+		assert.Equal(t, []stage{
+			{Name: "closed", Duration: time.Second, Concurrency: tt.concurrency, Ramp: rampStep},
+			{Name: "open", Duration: time.Second, Rate: 100, Ramp: rampLinear},
+		}, planStages(p), "worker %d", tt.index)
+	}
+	// The plan of the whole run is left as is.
+	assert.Equal(t, int32(5), plan.GetStages()[0].GetConcurrency())
+}
+
+func TestPlanWorkload(t *testing.T) {
+	plan, err := newPlan(nil, arrivalFixed, 1, 1, nil, &defaultProfile)
+	require.NoError(t, err)
+	next, err := planWorkload(plan)
+	require.NoError(t, err)
+	assert.Equal(t, int64(defaultProfile.Resources*defaultProfile.MetricsPerResource*defaultProfile.PointsPerMetric), countDataPoints(next()))
+
+	_, err = planWorkload(&pl.Plan{})
+	assert.Error(t, err)
+}
+
+func TestMergeWorkerStats(t *testing.T) {
+	stages := []stage{{Name: "first"}, {Name: "second"}}
+	worker := func(elapsed time.Duration, latency time.Duration) *pl.WorkerStats {
+		h := newHistogram()
+		h.record(latency)
+		return &pl.WorkerStats{Stages: []*pl.StageStats{
+			{Index: 0, Elapsed: durationpb.New(time.Second), Requests: &pl.Counts{Sent: 10, Accepted: 9, Rejected: 1}, Latencies: h.toProto()},
+			{Index: 1, Elapsed: durationpb.New(elapsed), Requests: &pl.Counts{Sent: 5, Accepted: 5}, LateRequests: 2, Latencies: h.toProto()},
+		}}
+	}
+
+	merged := mergeWorkerStats(stages, []*pl.WorkerStats{worker(300*time.Millisecond, time.Millisecond), worker(500*time.Millisecond, 3*time.Millisecond), nil})
+
+	require.Len(t, merged, 2)
+	sent, accepted, rejected := merged[0].requests.load()
+	assert.Equal(t, []int64{20, 18, 2}, []int64{sent, accepted, rejected})
+	assert.Equal(t, "second", merged[1].stage.Name)
+	assert.Equal(t, 500*time.Millisecond, merged[1].duration())
+	assert.Equal(t, int64(4), merged[1].late)
+	count, mean, _, _ := merged[1].latencies.snapshot()
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 2*time.Millisecond, mean)
+}
+
+// runWorkers runs the workers of a coordinator, each sending requests with its own client.
+func runWorkers(t *testing.T, c *coordinator, clients []*fakeMetricsClient) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, runDistributedWorker(context.Background(), c.address(), client))
+		}()
+	}
+	return &wg
+}
+
+func TestDistributedRun(t *testing.T) {
+	stages := []stage{
+		{Name: "closed", Duration: 200 * time.Millisecond, Concurrency: 2, Ramp: rampStep},
+		{Name: "open", Duration: 200 * time.Millisecond, Rate: 200, Ramp: rampStep},
+	}
+	plan, err := newPlan(stages, arrivalFixed, 4, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	c, err := newCoordinator("127.0.0.1:0", false, 2, plan, stages)
+	require.NoError(t, err)
+	defer c.close()
+
+	clients := []*fakeMetricsClient{{delay: time.Millisecond}, {delay: time.Millisecond}}
+	workers := runWorkers(t, c, clients)
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	_, err = c.waitForWorkers(ctx)
+	require.NoError(t, err)
+	stats := c.run(ctx, abort, abortThresholds{MaxErrorRate: 0.05, MinRequests: 1})
+	workers.Wait()
+
+	assert.NoError(t, context.Cause(ctx))
+	require.Len(t, stats, 2)
+	var total int64
+	for _, s := range stats {
+		sent, accepted, _ := s.requests.load()
+		assert.Positive(t, sent, s.stage.Name)
+		assert.Equal(t, sent, accepted, s.stage.Name)
+		total += sent
+	}
+	// 200 requests/s over 200ms, whatever the number of workers.
+	sent, _, _ := stats[1].requests.load()
+	assert.InDelta(t, 40, sent, 6)
+	assert.Equal(t, clients[0].calls.Load()+clients[1].calls.Load(), total)
+	assert.Positive(t, clients[0].calls.Load())
+	assert.Positive(t, clients[1].calls.Load())
+}
+
+func TestDistributedRun_Stop(t *testing.T) {
+	stages := []stage{{Name: "run", Concurrency: 2, Ramp: rampStep}}
+	plan, err := newPlan(stages, arrivalFixed, 2, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	c, err := newCoordinator("127.0.0.1:0", false, 2, plan, stages)
+	require.NoError(t, err)
+	defer c.close()
+
+	client := &fakeMetricsClient{delay: time.Millisecond}
+	client.fail.Store(true)
+	workers := runWorkers(t, c, []*fakeMetricsClient{client, client})
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	_, err = c.waitForWorkers(ctx)
+	require.NoError(t, err)
+	// The stage lasts until the end of the run, which the thresholds abort.
+	start := time.Now()
+	stats := c.run(ctx, abort, abortThresholds{MaxErrorRate: 0.05, MinRequests: 1})
+	workers.Wait()
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.ErrorIs(t, context.Cause(ctx), errAborted)
+	require.Len(t, stats, 1)
+	sent, _, rejected := stats[0].requests.load()
+	assert.Positive(t, sent)
+	assert.Equal(t, sent, rejected)
+}
+
+func TestCoordinator_RejectsExtraWorkers(t *testing.T) {
+	plan, err := newPlan(nil, arrivalFixed, 1, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	c, err := newCoordinator("127.0.0.1:0", false, 1, plan, nil)
+	require.NoError(t, err)
+	defer c.close()
+
+	_, err = c.register("first")
+	require.NoError(t, err)
+	_, err = c.register("second")
+	assert.Error(t, err)
+}
+
+func TestCoordinator_WorkerLeavesBeforeRegistration(t *testing.T) {
+	stages := []stage{{Name: "closed", Duration: 100 * time.Millisecond, Concurrency: 2, Ramp: rampStep}}
+	plan, err := newPlan(stages, arrivalFixed, 2, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	c, err := newCoordinator("127.0.0.1:0", false, 2, plan, stages)
+	require.NoError(t, err)
+	defer c.close()
+	registered := func() int {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.registered
+	}
+
+	// A worker registers and leaves before the others: its slot is taken by the next one.
+	conn, err := grpc.Dial(c.address(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pl.NewCoordinatorServiceClient(conn).Join(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pl.WorkerMessage{Message: &pl.WorkerMessage_Register{Register: &pl.Register{Name: "leaving"}}}))
+	require.Eventually(t, func() bool { return registered() == 1 }, 5*time.Second, time.Millisecond)
+	cancel()
+	require.Eventually(t, func() bool { return registered() == 0 }, 5*time.Second, time.Millisecond)
+
+	clients := []*fakeMetricsClient{{delay: time.Millisecond}, {delay: time.Millisecond}}
+	workers := runWorkers(t, c, clients)
+	runCtx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	_, err = c.waitForWorkers(runCtx)
+	require.NoError(t, err)
+	c.run(runCtx, abort, abortThresholds{})
+	workers.Wait()
+
+	assert.Positive(t, clients[0].calls.Load())
+	assert.Positive(t, clients[1].calls.Load())
+}
+
+func TestNewCoordinator_RemoteAddress(t *testing.T) {
+	plan, err := newPlan(nil, arrivalFixed, 1, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	for _, address := range []string{":0", "0.0.0.0:0", "192.0.2.1:7070"} {
+		_, err := newCoordinator(address, false, 1, plan, nil)
+		assert.ErrorContains(t, err, "loopback", address)
+	}
+
+	c, err := newCoordinator(":0", true, 1, plan, nil)
+	require.NoError(t, err)
+	c.close()
+	c, err = newCoordinator("localhost:0", false, 1, plan, nil)
+	require.NoError(t, err)
+	c.close()
+}
+
+func TestCoordinator_WorkerLeavesBeforeStart(t *testing.T) {
+	plan, err := newPlan(nil, arrivalFixed, 1, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	c, err := newCoordinator("127.0.0.1:0", false, 1, plan, nil)
+	require.NoError(t, err)
+	defer c.close()
+
+	index, err := c.register("leaving")
+	require.NoError(t, err)
+	c.leave(index)
+	_, err = c.waitForWorkers(context.Background())
+	assert.ErrorContains(t, err, "worker leaving left before the start of the run")
+}
+
+func TestCoordinator_RejectsInvalidStats(t *testing.T) {
+	stages := []stage{{Name: "closed", Duration: time.Second, Concurrency: 1, Ramp: rampStep}}
+	plan, err := newPlan(stages, arrivalFixed, 1, 1, emptyRequest(), nil)
+	require.NoError(t, err)
+	c, err := newCoordinator("127.0.0.1:0", false, 1, plan, stages)
+	require.NoError(t, err)
+	defer c.close()
+
+	conn, err := grpc.Dial(c.address(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := pl.NewCoordinatorServiceClient(conn).Join(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pl.WorkerMessage{Message: &pl.WorkerMessage_Register{Register: &pl.Register{Name: "hostile"}}}))
+	msg, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, msg.GetPlan())
+
+	// A histogram bucket far above the longest duration is not allocated.
+	stats := &pl.WorkerStats{Stages: []*pl.StageStats{{
+		Latencies: &pl.Histogram{Count: 1, Indexes: []int32{math.MaxInt32}, Counts: []int64{1}},
+	}}}
+	require.NoError(t, stream.Send(&pl.WorkerMessage{Message: &pl.WorkerMessage_Stats{Stats: stats}}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Nil(t, c.stats[0])
+}
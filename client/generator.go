@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric types the generator can produce.
+const (
+	metricTypeGauge                = "gauge"
+	metricTypeSum                  = "sum"
+	metricTypeHistogram            = "histogram"
+	metricTypeExponentialHistogram = "exponential_histogram"
+	metricTypeSummary              = "summary"
+)
+
+var metricTypes = []string{metricTypeGauge, metricTypeSum, metricTypeHistogram, metricTypeExponentialHistogram, metricTypeSummary}
+
+// observationsPerPoint is the number of values aggregated by a generated histogram or summary data
+// point.
+const observationsPerPoint = 10
+
+// histogramBounds are the explicit bucket bounds of the generated histograms.
+var histogramBounds = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// profile describes the requests made by the generator.
+type profile struct {
+	Resources            int      `mapstructure:"resources" json:"resources"`
+	MetricsPerResource   int      `mapstructure:"metrics_per_resource" json:"metrics_per_resource"`
+	MetricTypes          []string `mapstructure:"metric_types" json:"metric_types"` // Assigned to the metrics of a resource in turn
+	AttributeCardinality int      `mapstructure:"attribute_cardinality" json:"attribute_cardinality"`
+	PointsPerMetric      int      `mapstructure:"points_per_metric" json:"points_per_metric"`
+}
+
+var defaultProfile = profile{
+	Resources:            1,
+	MetricsPerResource:   10,
+	MetricTypes:          []string{metricTypeGauge, metricTypeSum, metricTypeHistogram, metricTypeExponentialHistogram},
+	AttributeCardinality: 10,
+	PointsPerMetric:      1,
+}
+
+// loadProfile reads a YAML profile. The settings it leaves out keep their default value.
+func loadProfile(path string) (profile, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetDefault("resources", defaultProfile.Resources)
+	v.SetDefault("metrics_per_resource", defaultProfile.MetricsPerResource)
+	v.SetDefault("metric_types", defaultProfile.MetricTypes)
+	v.SetDefault("attribute_cardinality", defaultProfile.AttributeCardinality)
+	v.SetDefault("points_per_metric", defaultProfile.PointsPerMetric)
+	if err := v.ReadInConfig(); err != nil {
+		return profile{}, err
+	}
+	var p profile
+	if err := v.Unmarshal(&p); err != nil {
+		return profile{}, err
+	}
+	return p, nil
+}
+
+func (p profile) validate() error {
+	switch {
+	case p.Resources < 1:
+		return fmt.Errorf("resources must be at least 1")
+	case p.MetricsPerResource < 1:
+		return fmt.Errorf("metrics per resource must be at least 1")
+	case p.AttributeCardinality < 1:
+		return fmt.Errorf("attribute cardinality must be at least 1")
+	case p.PointsPerMetric < 1:
+		return fmt.Errorf("points per metric must be at least 1")
+	case len(p.MetricTypes) == 0:
+		return fmt.Errorf("at least one metric type is required")
+	}
+	for _, metricType := range p.MetricTypes {
+		if !slices.Contains(metricTypes, metricType) {
+			return fmt.Errorf("unknown metric type %q, expected one of %s", metricType, strings.Join(metricTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// generator builds export requests following a profile. Their data points have the current time
+// and random values, and their attributes cycle through AttributeCardinality distinct sets. The
+// sums and histograms have the delta temporality, starting when the previous request was built.
+//
+// The values and attributes of a request only depend on the seed and on the number of the request,
+// so they do not depend on the order in which concurrent callers build them. It is safe for
+// concurrent use, and the requests are built concurrently.
+type generator struct {
+	profile profile
+	seed    uint64
+
+	mutex    sync.Mutex
+	last     time.Time
+	requests int
+}
+
+func newGenerator(p profile, seed uint64) *generator {
+	return &generator{
+		profile: p,
+		seed:    seed,
+		last:    time.Now(),
+	}
+}
+
+// requestBuilder builds a request of a generator, with a random source of its own.
+type requestBuilder struct {
+	profile profile
+	rng     *rand.Rand
+	number  int // Of the request among those of the generator
+}
+
+// next builds a new export request.
+func (g *generator) next() *pb.ExportMetricsServiceRequest {
+	return g.build(time.Now())
+}
+
+// build builds the export request of the time.
+func (g *generator) build(now time.Time) *pb.ExportMetricsServiceRequest {
+	g.mutex.Lock()
+	start, end := uint64(g.last.UnixNano()), uint64(now.UnixNano())
+	g.last = now
+	number := g.requests
+	g.requests++
+	g.mutex.Unlock()
+
+	b := requestBuilder{profile: g.profile, rng: rand.New(rand.NewPCG(g.seed, uint64(number))), number: number}
+	req := &pb.ExportMetricsServiceRequest{}
+	for r := 0; r < g.profile.Resources; r++ {
+		metrics := make([]*v1.Metric, 0, g.profile.MetricsPerResource)
+		for m := 0; m < g.profile.MetricsPerResource; m++ {
+			metricType := g.profile.MetricTypes[m%len(g.profile.MetricTypes)]
+			metric := &v1.Metric{
+				Name:        fmt.Sprintf("synthetic.%s.%d", metricType, m),
+				Description: fmt.Sprintf("Synthetic %s generated by the load test client", metricType),
+				Unit:        "1",
+			}
+			for p := 0; p < g.profile.PointsPerMetric; p++ {
+				b.addDataPoint(metric, metricType, b.attributes(p), start, end)
+			}
+			metrics = append(metrics, metric)
+		}
+		req.ResourceMetrics = append(req.ResourceMetrics, &v1.ResourceMetrics{
+			Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{
+				stringAttribute("service.name", "loadtest"),
+				stringAttribute("service.instance.id", fmt.Sprintf("instance-%d", r)),
+			}},
+			ScopeMetrics: []*v1.ScopeMetrics{{
+				Scope:   &commonv1.InstrumentationScope{Name: "metrics/client"},
+				Metrics: metrics,
+			}},
+		})
+	}
+	return req
+}
+
+// attributes returns the attributes of the p-th data point of a metric in the request.
+func (b requestBuilder) attributes(p int) []*commonv1.KeyValue {
+	series := (b.number*b.profile.PointsPerMetric + p) % b.profile.AttributeCardinality
+	return []*commonv1.KeyValue{stringAttribute("series", fmt.Sprintf("series-%d", series))}
+}
+
+// addDataPoint adds a data point with random values to a metric of the type.
+func (b requestBuilder) addDataPoint(metric *v1.Metric, metricType string, attributes []*commonv1.KeyValue, start, end uint64) {
+	switch metricType {
+	case metricTypeGauge:
+		gauge := metric.GetGauge()
+		if gauge == nil {
+			gauge = &v1.Gauge{}
+			metric.Data = &v1.Metric_Gauge{Gauge: gauge}
+		}
+		gauge.DataPoints = append(gauge.DataPoints, &v1.NumberDataPoint{
+			Attributes:   attributes,
+			TimeUnixNano: end,
+			Value:        &v1.NumberDataPoint_AsDouble{AsDouble: b.rng.NormFloat64()*10 + 100},
+		})
+	case metricTypeSum:
+		sum := metric.GetSum()
+		if sum == nil {
+			sum = &v1.Sum{AggregationTemporality: v1.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, IsMonotonic: true}
+			metric.Data = &v1.Metric_Sum{Sum: sum}
+		}
+		sum.DataPoints = append(sum.DataPoints, &v1.NumberDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Value:             &v1.NumberDataPoint_AsInt{AsInt: b.rng.Int64N(100)},
+		})
+	case metricTypeHistogram:
+		histogram := metric.GetHistogram()
+		if histogram == nil {
+			histogram = &v1.Histogram{AggregationTemporality: v1.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA}
+			metric.Data = &v1.Metric_Histogram{Histogram: histogram}
+		}
+		values := b.observations()
+		point := &v1.HistogramDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             uint64(len(values)),
+			Sum:               proto64(floatSum(values)),
+			Min:               proto64(values[0]),
+			Max:               proto64(values[len(values)-1]),
+			ExplicitBounds:    histogramBounds,
+			BucketCounts:      make([]uint64, len(histogramBounds)+1),
+		}
+		for _, value := range values {
+			index, _ := slices.BinarySearch(histogramBounds, value)
+			point.BucketCounts[index]++
+		}
+		histogram.DataPoints = append(histogram.DataPoints, point)
+	case metricTypeExponentialHistogram:
+		histogram := metric.GetExponentialHistogram()
+		if histogram == nil {
+			histogram = &v1.ExponentialHistogram{AggregationTemporality: v1.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA}
+			metric.Data = &v1.Metric_ExponentialHistogram{ExponentialHistogram: histogram}
+		}
+		histogram.DataPoints = append(histogram.DataPoints, exponentialDataPoint(b.observations(), attributes, start, end))
+	case metricTypeSummary:
+		summary := metric.GetSummary()
+		if summary == nil {
+			summary = &v1.Summary{}
+			metric.Data = &v1.Metric_Summary{Summary: summary}
+		}
+		values := b.observations()
+		point := &v1.SummaryDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             uint64(len(values)),
+			Sum:               floatSum(values),
+		}
+		for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+			point.QuantileValues = append(point.QuantileValues, &v1.SummaryDataPoint_ValueAtQuantile{
+				Quantile: q,
+				Value:    values[int(q*float64(len(values)-1))],
+			})
+		}
+		summary.DataPoints = append(summary.DataPoints, point)
+	}
+}
+
+// observations returns the sorted values aggregated by a histogram or summary data point, spread
+// over the buckets of histogramBounds.
+func (b requestBuilder) observations() []float64 {
+	values := make([]float64, observationsPerPoint)
+	for i := range values {
+		values[i] = b.rng.ExpFloat64() * 50
+	}
+	slices.Sort(values)
+	return values
+}
+
+// exponentialDataPoint aggregates sorted positive values in an exponential histogram data point of
+// scale 0, whose bucket i holds the values in (2^i, 2^(i+1)].
+func exponentialDataPoint(values []float64, attributes []*commonv1.KeyValue, start, end uint64) *v1.ExponentialHistogramDataPoint {
+	point := &v1.ExponentialHistogramDataPoint{
+		Attributes:        attributes,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      end,
+		Count:             uint64(len(values)),
+		Sum:               proto64(floatSum(values)),
+		Min:               proto64(values[0]),
+		Max:               proto64(values[len(values)-1]),
+		Positive:          &v1.ExponentialHistogramDataPoint_Buckets{},
+	}
+	for _, value := range values {
+		if value == 0 {
+			point.ZeroCount++
+			continue
+		}
+		index := int32(math.Ceil(math.Log2(value))) - 1
+		buckets := point.Positive
+		if len(buckets.BucketCounts) == 0 {
+			buckets.Offset = index
+		}
+		// The values are sorted, so the buckets only grow upwards.
+		for int32(len(buckets.BucketCounts)) <= index-buckets.Offset {
+			buckets.BucketCounts = append(buckets.BucketCounts, 0)
+		}
+		buckets.BucketCounts[index-buckets.Offset]++
+	}
+	return point
+}
+
+func floatSum(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum
+}
+
+func proto64(v float64) *float64 {
+	return &v
+}
+
+func stringAttribute(key, value string) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Build(t *testing.T) {
+	p := profile{
+		Resources:            2,
+		MetricsPerResource:   5,
+		MetricTypes:          metricTypes,
+		AttributeCardinality: 3,
+		PointsPerMetric:      2,
+	}
+	g := newGenerator(p, 1)
+	now := time.Unix(1700000000, 0)
+
+	req := g.build(now)
+	assert.Equal(t, int64(2*5*2), countDataPoints(req))
+	require.Len(t, req.ResourceMetrics, 2)
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 5)
+	for _, metric := range metrics {
+		assert.NotEmpty(t, metric.Name)
+		assert.NotEmpty(t, metric.Description)
+		assert.NotEmpty(t, metric.Unit)
+	}
+	assert.IsType(t, &v1.Metric_Gauge{}, metrics[0].Data)
+	assert.IsType(t, &v1.Metric_Sum{}, metrics[1].Data)
+	assert.IsType(t, &v1.Metric_Histogram{}, metrics[2].Data)
+	assert.IsType(t, &v1.Metric_ExponentialHistogram{}, metrics[3].Data)
+	assert.IsType(t, &v1.Metric_Summary{}, metrics[4].Data)
+
+	point := metrics[0].GetGauge().DataPoints[0]
+	assert.Equal(t, uint64(now.UnixNano()), point.TimeUnixNano)
+
+	// The attribute sets cycle through the cardinality from one request to the next.
+	var series []string
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			req = g.build(now)
+		}
+		for _, point := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetGauge().DataPoints {
+			series = append(series, point.Attributes[0].Value.GetStringValue())
+		}
+	}
+	assert.Equal(t, []string{"series-0", "series-1", "series-2", "series-0", "series-1", "series-2"}, series)
+}
+
+func TestGenerator_Seed(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	build := func(seed uint64) []byte {
+		g := newGenerator(defaultProfile, seed)
+		g.last = start
+		g.build(start.Add(time.Second))
+		b, err := proto.Marshal(g.build(start.Add(2 * time.Second)))
+		require.NoError(t, err)
+		return b
+	}
+
+	assert.Equal(t, build(42), build(42))
+	assert.NotEqual(t, build(42), build(43))
+}
+
+func TestGenerator_Concurrent(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	newTestGenerator := func() *generator {
+		g := newGenerator(defaultProfile, 42)
+		g.last = now
+		return g
+	}
+	marshal := func(req *pb.ExportMetricsServiceRequest) string {
+		b, err := proto.Marshal(req)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	g := newTestGenerator()
+	var sequential []string
+	for range 20 {
+		sequential = append(sequential, marshal(g.build(now)))
+	}
+
+	// The requests built concurrently are the same, whatever the order they are built in.
+	g = newTestGenerator()
+	concurrent := make([]string, 20)
+	var wg sync.WaitGroup
+	for i := range concurrent {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			concurrent[i] = marshal(g.build(now))
+		}()
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, sequential, concurrent)
+}
+
+func TestGenerator_HistogramBuckets(t *testing.T) {
+	p := defaultProfile
+	p.MetricTypes = []string{metricTypeHistogram, metricTypeExponentialHistogram}
+	req := newGenerator(p, 7).next()
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+
+	histogram := metrics[0].GetHistogram().DataPoints[0]
+	assert.Len(t, histogram.BucketCounts, len(histogram.ExplicitBounds)+1)
+	assert.Equal(t, histogram.Count, sum(histogram.BucketCounts))
+
+	exponential := metrics[1].GetExponentialHistogram().DataPoints[0]
+	assert.Equal(t, exponential.Count, exponential.ZeroCount+sum(exponential.Positive.BucketCounts))
+}
+
+func TestExponentialDataPoint(t *testing.T) {
+	point := exponentialDataPoint([]float64{0, 0.75, 1, 3, 4, 4.5}, nil, 0, 0)
+	assert.Equal(t, uint64(1), point.ZeroCount)
+	// Buckets (0.5, 1], (1, 2], (2, 4], (4, 8].
+	assert.Equal(t, int32(-1), point.Positive.Offset)
+	assert.Equal(t, []uint64{2, 0, 2, 1}, point.Positive.BucketCounts)
+}
+
+func TestProfile_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *profile)
+		valid  bool
+	}{
+		{"default", func(p *profile) {}, true},
+		{"no resources", func(p *profile) { p.Resources = 0 }, false},
+		{"no metrics", func(p *profile) { p.MetricsPerResource = 0 }, false},
+		{"no points", func(p *profile) { p.PointsPerMetric = 0 }, false},
+		{"no cardinality", func(p *profile) { p.AttributeCardinality = 0 }, false},
+		{"no metric types", func(p *profile) { p.MetricTypes = nil }, false},
+		{"unknown metric type", func(p *profile) { p.MetricTypes = []string{"counter"} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := defaultProfile
+			tt.modify(&p)
+			if tt.valid {
+				assert.NoError(t, p.validate())
+			} else {
+				assert.Error(t, p.validate())
+			}
+		})
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("resources: 3\nmetric_types: [sum, summary]\n"), 0o600))
+
+	p, err := loadProfile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, p.Resources)
+	assert.Equal(t, []string{metricTypeSum, metricTypeSummary}, p.MetricTypes)
+	assert.Equal(t, defaultProfile.MetricsPerResource, p.MetricsPerResource)
+
+	p, err = loadProfile("profiles/example.yaml")
+	require.NoError(t, err)
+	assert.NoError(t, p.validate())
+}
+
+func sum(counts []uint64) uint64 {
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
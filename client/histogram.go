@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"metrics/client/pb/pl"
+	"sync"
+	"time"
+)
+
+// subBucketBits sets the precision of the histogram: every power of two is split in 2^7 linear
+// sub-buckets, so a recorded value is off by less than 1/128 of itself.
+const subBucketBits = 7
+
+const subBucketCount = 1 << subBucketBits
+
+// maxBucketIndex is the index of the bucket of the longest duration.
+var maxBucketIndex = bucketIndex(math.MaxInt64)
+
+// histogram records durations with a bounded relative error, like an HDR histogram. Values below
+// 2*subBucketCount nanoseconds are recorded exactly; above, every power of two is split into
+// subBucketCount buckets of equal width. It is safe for concurrent use.
+type histogram struct {
+	mutex  sync.Mutex
+	counts []int64
+	count  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func newHistogram() *histogram {
+	return &histogram{}
+}
+
+// bucketIndex returns the index of the bucket of a value.
+func bucketIndex(v int64) int {
+	shift := max(bits.Len64(uint64(v))-subBucketBits-1, 0)
+	return shift*subBucketCount + int(v>>shift)
+}
+
+// bucketValue returns the value a bucket stands for, the middle of its range.
+func bucketValue(index int) int64 {
+	if index < 2*subBucketCount {
+		return int64(index)
+	}
+	shift := index/subBucketCount - 1
+	lower := int64(index-shift*subBucketCount) << shift
+	return lower + (int64(1)<<shift)/2
+}
+
+// record adds a duration to the histogram. Negative durations are recorded as 0.
+func (h *histogram) record(d time.Duration) {
+	d = max(d, 0)
+	index := bucketIndex(int64(d))
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if index >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, index-len(h.counts)+1)...)
+	}
+	h.counts[index]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	h.max = max(h.max, d)
+	h.count++
+	h.sum += d
+}
+
+// quantile returns the value below which the fraction q of the recorded values fall, or 0 if
+// nothing was recorded.
+func (h *histogram) quantile(q float64) time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := int64(q*float64(h.count) + 0.5)
+	rank = min(max(rank, 1), h.count)
+	var seen int64
+	for index, count := range h.counts {
+		if seen += count; seen >= rank {
+			// The exact extremes are known.
+			return min(max(time.Duration(bucketValue(index)), h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// snapshot returns the number of recorded values, their mean, minimum and maximum.
+func (h *histogram) snapshot() (count int64, mean, minimum, maximum time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.count == 0 {
+		return 0, 0, 0, 0
+	}
+	return h.count, h.sum / time.Duration(h.count), h.min, h.max
+}
+
+// drain returns a histogram holding the values recorded so far, and empties this one.
+func (h *histogram) drain() *histogram {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	drained := &histogram{counts: h.counts, count: h.count, sum: h.sum, min: h.min, max: h.max}
+	h.counts, h.count, h.sum, h.min, h.max = nil, 0, 0, 0, 0
+	return drained
+}
+
+// toProto returns the buckets holding values and the statistics of the histogram, for a worker to
+// send them to the coordinator.
+func (h *histogram) toProto() *pl.Histogram {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	p := &pl.Histogram{Count: h.count, Sum: int64(h.sum), Min: int64(h.min), Max: int64(h.max)}
+	for index, count := range h.counts {
+		if count > 0 {
+			p.Indexes = append(p.Indexes, int32(index))
+			p.Counts = append(p.Counts, count)
+		}
+	}
+	return p
+}
+
+// checkHistogram returns an error if a histogram sent by a worker has buckets that do not exist.
+func checkHistogram(p *pl.Histogram) error {
+	if len(p.GetIndexes()) != len(p.GetCounts()) {
+		return fmt.Errorf("%d bucket indexes for %d counts", len(p.GetIndexes()), len(p.GetCounts()))
+	}
+	for _, index := range p.GetIndexes() {
+		if index < 0 || int(index) > maxBucketIndex {
+			return fmt.Errorf("invalid bucket index %d", index)
+		}
+	}
+	return nil
+}
+
+// merge adds the values of a histogram sent by a worker to this one. The buckets that do not
+// exist are ignored.
+func (h *histogram) merge(p *pl.Histogram) {
+	if p.GetCount() == 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, index := range p.GetIndexes() {
+		if index < 0 || int(index) > maxBucketIndex || i >= len(p.GetCounts()) {
+			continue
+		}
+		if int(index) >= len(h.counts) {
+			h.counts = append(h.counts, make([]int64, int(index)-len(h.counts)+1)...)
+		}
+		h.counts[index] += p.GetCounts()[i]
+	}
+	if h.count == 0 || time.Duration(p.GetMin()) < h.min {
+		h.min = time.Duration(p.GetMin())
+	}
+	h.max = max(h.max, time.Duration(p.GetMax()))
+	h.count += p.GetCount()
+	h.sum += time.Duration(p.GetSum())
+}
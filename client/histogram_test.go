@@ -0,0 +1,119 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"metrics/client/pb/pl"
+	"testing"
+	"time"
+)
+
+func TestBucketValue_RelativeError(t *testing.T) {
+	values := []int64{0, 1, 255, 256, 257, 1000, 123456, int64(time.Second), int64(time.Hour)}
+	for _, v := range values {
+		got := bucketValue(bucketIndex(v))
+		assert.InDelta(t, v, got, float64(v)/subBucketCount+1, "value %d", v)
+	}
+}
+
+func TestBucketIndex_Monotonic(t *testing.T) {
+	previous := bucketIndex(0)
+	for v := int64(1); v < 1<<20; v += 7 {
+		index := bucketIndex(v)
+		assert.GreaterOrEqual(t, index, previous, "value %d", v)
+		previous = index
+	}
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	h := newHistogram()
+	assert.Equal(t, time.Duration(0), h.quantile(0.5))
+
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{0.5, 500 * time.Millisecond},
+		{0.9, 900 * time.Millisecond},
+		{0.99, 990 * time.Millisecond},
+		{1, 1000 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, h.quantile(tt.q), float64(tt.want)/subBucketCount, "quantile %v", tt.q)
+	}
+
+	count, mean, minimum, maximum := h.snapshot()
+	assert.Equal(t, int64(1000), count)
+	assert.Equal(t, 500500*time.Microsecond, mean)
+	assert.Equal(t, time.Millisecond, minimum)
+	assert.Equal(t, time.Second, maximum)
+}
+
+func TestHistogram_Drain(t *testing.T) {
+	h := newHistogram()
+	h.record(time.Millisecond)
+	h.record(3 * time.Millisecond)
+
+	drained := h.drain()
+	count, mean, _, _ := drained.snapshot()
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 2*time.Millisecond, mean)
+
+	count, _, _, _ = h.snapshot()
+	assert.Equal(t, int64(0), count)
+	h.record(5 * time.Millisecond)
+	_, _, minimum, maximum := h.snapshot()
+	assert.Equal(t, 5*time.Millisecond, minimum)
+	assert.Equal(t, 5*time.Millisecond, maximum)
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	a, b := newHistogram(), newHistogram()
+	for i := 1; i <= 500; i++ {
+		a.record(time.Duration(i) * time.Millisecond)
+		b.record(time.Duration(500+i) * time.Millisecond)
+	}
+
+	merged := newHistogram()
+	merged.merge(a.toProto())
+	merged.merge(b.toProto())
+	merged.merge(newHistogram().toProto())
+
+	count, mean, minimum, maximum := merged.snapshot()
+	assert.Equal(t, int64(1000), count)
+	assert.Equal(t, 500500*time.Microsecond, mean)
+	assert.Equal(t, time.Millisecond, minimum)
+	assert.Equal(t, time.Second, maximum)
+	assert.InDelta(t, 500*time.Millisecond, merged.quantile(0.5), float64(500*time.Millisecond)/subBucketCount)
+	assert.InDelta(t, 990*time.Millisecond, merged.quantile(0.99), float64(990*time.Millisecond)/subBucketCount)
+}
+
+func TestCheckHistogram(t *testing.T) {
+	h := newHistogram()
+	h.record(time.Duration(math.MaxInt64))
+	assert.NoError(t, checkHistogram(h.toProto()))
+
+	tests := []struct {
+		name string
+		p    *pl.Histogram
+	}{
+		{"negative index", &pl.Histogram{Count: 1, Indexes: []int32{-1}, Counts: []int64{1}}},
+		{"index above the longest duration", &pl.Histogram{Count: 1, Indexes: []int32{math.MaxInt32}, Counts: []int64{1}}},
+		{"missing count", &pl.Histogram{Count: 1, Indexes: []int32{1, 2}, Counts: []int64{1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, checkHistogram(tt.p))
+
+			// The invalid buckets are not merged.
+			merged := newHistogram()
+			merged.merge(tt.p)
+			assert.LessOrEqual(t, len(merged.counts), maxBucketIndex+1)
+		})
+	}
+}
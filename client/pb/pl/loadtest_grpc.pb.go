@@ -0,0 +1,147 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.26.1
+// source: loadtest.proto
+
+package pl
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CoordinatorService_Join_FullMethodName = "/loadtest.CoordinatorService/Join"
+)
+
+// CoordinatorServiceClient is the client API for CoordinatorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CoordinatorServiceClient interface {
+	// Join registers a worker for the run.
+	// The worker first sends a Register message. Once the expected number of workers have registered, the coordinator sends each of them a Plan, which tells when to start.
+	// The worker then sends its statistics periodically, and a last time with final set once its run is over. The coordinator sends a Stop message to end the run early.
+	Join(ctx context.Context, opts ...grpc.CallOption) (CoordinatorService_JoinClient, error)
+}
+
+type coordinatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCoordinatorServiceClient(cc grpc.ClientConnInterface) CoordinatorServiceClient {
+	return &coordinatorServiceClient{cc}
+}
+
+func (c *coordinatorServiceClient) Join(ctx context.Context, opts ...grpc.CallOption) (CoordinatorService_JoinClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CoordinatorService_ServiceDesc.Streams[0], CoordinatorService_Join_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &coordinatorServiceJoinClient{stream}
+	return x, nil
+}
+
+type CoordinatorService_JoinClient interface {
+	Send(*WorkerMessage) error
+	Recv() (*CoordinatorMessage, error)
+	grpc.ClientStream
+}
+
+type coordinatorServiceJoinClient struct {
+	grpc.ClientStream
+}
+
+func (x *coordinatorServiceJoinClient) Send(m *WorkerMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *coordinatorServiceJoinClient) Recv() (*CoordinatorMessage, error) {
+	m := new(CoordinatorMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CoordinatorServiceServer is the server API for CoordinatorService service.
+// All implementations must embed UnimplementedCoordinatorServiceServer
+// for forward compatibility
+type CoordinatorServiceServer interface {
+	// Join registers a worker for the run.
+	// The worker first sends a Register message. Once the expected number of workers have registered, the coordinator sends each of them a Plan, which tells when to start.
+	// The worker then sends its statistics periodically, and a last time with final set once its run is over. The coordinator sends a Stop message to end the run early.
+	Join(CoordinatorService_JoinServer) error
+	mustEmbedUnimplementedCoordinatorServiceServer()
+}
+
+// UnimplementedCoordinatorServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCoordinatorServiceServer struct {
+}
+
+func (UnimplementedCoordinatorServiceServer) Join(CoordinatorService_JoinServer) error {
+	return status.Errorf(codes.Unimplemented, "method Join not implemented")
+}
+func (UnimplementedCoordinatorServiceServer) mustEmbedUnimplementedCoordinatorServiceServer() {}
+
+// UnsafeCoordinatorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CoordinatorServiceServer will
+// result in compilation errors.
+type UnsafeCoordinatorServiceServer interface {
+	mustEmbedUnimplementedCoordinatorServiceServer()
+}
+
+func RegisterCoordinatorServiceServer(s grpc.ServiceRegistrar, srv CoordinatorServiceServer) {
+	s.RegisterService(&CoordinatorService_ServiceDesc, srv)
+}
+
+func _CoordinatorService_Join_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CoordinatorServiceServer).Join(&coordinatorServiceJoinServer{stream})
+}
+
+type CoordinatorService_JoinServer interface {
+	Send(*CoordinatorMessage) error
+	Recv() (*WorkerMessage, error)
+	grpc.ServerStream
+}
+
+type coordinatorServiceJoinServer struct {
+	grpc.ServerStream
+}
+
+func (x *coordinatorServiceJoinServer) Send(m *CoordinatorMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *coordinatorServiceJoinServer) Recv() (*WorkerMessage, error) {
+	m := new(WorkerMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CoordinatorService_ServiceDesc is the grpc.ServiceDesc for CoordinatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CoordinatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "loadtest.CoordinatorService",
+	HandlerType: (*CoordinatorServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Join",
+			Handler:       _CoordinatorService_Join_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "loadtest.proto",
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balancing policies of the calls between the targets.
+const (
+	balancingRoundRobin = "round_robin" // Every connection of every target in turn
+	balancingPickFirst  = "pick_first"  // The ready connections of the first target with a ready one
+)
+
+// pool spreads the Export calls over several connections to each of several targets, and keeps
+// statistics per connection. Every connection is a separate gRPC channel, so a separate HTTP/2
+// connection which the server may close, for instance after its MaxConnectionAge, and which is
+// reopened by the next call on it. It is safe for concurrent use.
+type pool struct {
+	policy  string
+	targets []*target
+	conns   []*poolConn // Connections of all the targets, interleaved
+	next    atomic.Uint64
+
+	cancel   context.CancelFunc // Stops the watchers
+	watchers sync.WaitGroup
+}
+
+// target is a server address and the connections to it.
+type target struct {
+	address string
+	conns   []*poolConn
+}
+
+// poolConn is a connection of the pool and the statistics of the calls made on it.
+type poolConn struct {
+	target    string
+	index     int // Index of the connection among those to the target
+	conn      *grpc.ClientConn
+	client    pb.MetricsServiceClient
+	requests  counts
+	latencies *histogram
+
+	connects    int64 // Times the connection became ready
+	disconnects int64 // Times the connection stopped being ready
+}
+
+// newPool opens connections connections to each target with dial, which does not block, and
+// balances the calls between them with the policy.
+func newPool(addresses []string, connections int, policy string, dial func(address string) (*grpc.ClientConn, error)) (*pool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &pool{policy: policy, cancel: cancel}
+	for _, address := range addresses {
+		t := &target{address: address}
+		for i := range connections {
+			conn, err := dial(address)
+			if err != nil {
+				p.close()
+				return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+			}
+			c := &poolConn{target: address, index: i, conn: conn, client: pb.NewMetricsServiceClient(conn), latencies: newHistogram()}
+			t.conns = append(t.conns, c)
+			p.watchers.Add(1)
+			go func() {
+				defer p.watchers.Done()
+				c.watch(ctx)
+			}()
+		}
+		p.targets = append(p.targets, t)
+	}
+	for i := range connections {
+		for _, t := range p.targets {
+			p.conns = append(p.conns, t.conns[i])
+		}
+	}
+	return p, nil
+}
+
+// conn returns the first connection of the first target, for the calls besides Export.
+func (p *pool) conn() *grpc.ClientConn {
+	return p.conns[0].conn
+}
+
+// close closes every connection.
+func (p *pool) close() {
+	p.cancel()
+	for _, t := range p.targets {
+		for _, c := range t.conns {
+			c.conn.Close()
+		}
+	}
+	p.watchers.Wait()
+}
+
+// Export makes the call on the connection picked by the balancing policy, and records its outcome
+// and latency in the statistics of the connection, unless the context is done first.
+func (p *pool) Export(ctx context.Context, req *pb.ExportMetricsServiceRequest, opts ...grpc.CallOption) (*pb.ExportMetricsServiceResponse, error) {
+	c := p.pick()
+	start := time.Now()
+	resp, err := c.client.Export(ctx, req, opts...)
+	if ctx.Err() == nil {
+		var accepted int64
+		if err == nil {
+			accepted = 1
+		}
+		c.requests.add(1, accepted)
+		c.latencies.record(time.Since(start))
+	}
+	return resp, err
+}
+
+// pick returns the connection of the next call.
+func (p *pool) pick() *poolConn {
+	n := p.next.Add(1) - 1
+	if p.policy == balancingPickFirst {
+		for _, t := range p.targets {
+			var ready []*poolConn
+			for _, c := range t.conns {
+				switch c.conn.GetState() {
+				case connectivity.Ready:
+					ready = append(ready, c)
+				case connectivity.Idle:
+					// A connection closed by the server is only reopened by a call, which it
+					// does not get while it is not ready.
+					c.conn.Connect()
+				}
+			}
+			if len(ready) > 0 {
+				return ready[n%uint64(len(ready))]
+			}
+		}
+		// Without a ready connection, the calls go to the first target.
+		conns := p.targets[0].conns
+		return conns[n%uint64(len(conns))]
+	}
+	return p.conns[n%uint64(len(p.conns))]
+}
+
+// watch counts and logs the state changes of the connection until the context is done. The
+// initial connection is not logged.
+func (c *poolConn) watch(ctx context.Context) {
+	state := c.conn.GetState()
+	if state == connectivity.Ready {
+		atomic.AddInt64(&c.connects, 1)
+	}
+	for c.conn.WaitForStateChange(ctx, state) {
+		next := c.conn.GetState()
+		switch {
+		case next == connectivity.Ready:
+			if atomic.AddInt64(&c.connects, 1) > 1 {
+				log.Printf("Connection %d to %s reconnected", c.index+1, c.target)
+			}
+		case state == connectivity.Ready:
+			atomic.AddInt64(&c.disconnects, 1)
+			log.Printf("Connection %d to %s disconnected, now %s", c.index+1, c.target, next)
+		}
+		state = next
+	}
+}
+
+// reconnects returns the number of times the connection was reopened.
+func (c *poolConn) reconnects() int64 {
+	return max(atomic.LoadInt64(&c.connects)-1, 0)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingMetricsServer accepts every Export call and counts them.
+type countingMetricsServer struct {
+	pb.UnimplementedMetricsServiceServer
+	calls atomic.Int64
+}
+
+func (s *countingMetricsServer) Export(context.Context, *pb.ExportMetricsServiceRequest) (*pb.ExportMetricsServiceResponse, error) {
+	s.calls.Add(1)
+	return &pb.ExportMetricsServiceResponse{}, nil
+}
+
+// startMetricsServer serves a countingMetricsServer on a loopback port until the test ends.
+func startMetricsServer(t *testing.T, opts ...grpc.ServerOption) (string, *countingMetricsServer, *grpc.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	metrics := &countingMetricsServer{}
+	pb.RegisterMetricsServiceServer(server, metrics)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), metrics, server
+}
+
+func dialInsecure(address string) (*grpc.ClientConn, error) {
+	return grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	first, firstServer, _ := startMetricsServer(t)
+	second, secondServer, _ := startMetricsServer(t)
+	p, err := newPool([]string{first, second}, 2, balancingRoundRobin, dialInsecure)
+	require.NoError(t, err)
+	defer p.close()
+
+	for range 40 {
+		_, err := p.Export(context.Background(), emptyRequest())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int64(20), firstServer.calls.Load())
+	assert.Equal(t, int64(20), secondServer.calls.Load())
+	for _, c := range p.conns {
+		sent, accepted, _ := c.requests.load()
+		assert.Equal(t, int64(10), sent)
+		assert.Equal(t, int64(10), accepted)
+	}
+
+	var summary bytes.Buffer
+	writeTargets(&summary, p)
+	assert.Contains(t, summary.String(), "Targets (round_robin balancing):\n  "+first+": 20 sent, 20 accepted, 0 rejected, 0 disconnects, 0 reconnects")
+	assert.Contains(t, summary.String(), "    Connection 2: 10 sent, 10 accepted, 0 rejected")
+}
+
+func TestPool_PickFirst(t *testing.T) {
+	first, firstServer, server := startMetricsServer(t)
+	second, secondServer, _ := startMetricsServer(t)
+	p, err := newPool([]string{first, second}, 2, balancingPickFirst, dialInsecure)
+	require.NoError(t, err)
+	defer p.close()
+
+	for range 20 {
+		_, err := p.Export(context.Background(), emptyRequest())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(20), firstServer.calls.Load())
+	assert.Zero(t, secondServer.calls.Load())
+
+	// Once the first target is down, the calls go to the second one.
+	server.Stop()
+	for _, c := range p.targets[0].conns {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		for c.conn.GetState() == connectivity.Ready && c.conn.WaitForStateChange(ctx, connectivity.Ready) {
+		}
+		cancel()
+	}
+	require.Eventually(t, func() bool {
+		_, err := p.Export(context.Background(), emptyRequest())
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Positive(t, secondServer.calls.Load())
+	assert.Positive(t, atomic.LoadInt64(&p.targets[0].conns[0].disconnects))
+}
+
+func TestPool_PickFirst_ReadyConnections(t *testing.T) {
+	address, _, _ := startMetricsServer(t)
+	p, err := newPool([]string{address}, 2, balancingPickFirst, dialInsecure)
+	require.NoError(t, err)
+	defer p.close()
+	for _, c := range p.conns {
+		c.conn.Connect()
+		require.Eventually(t, func() bool { return c.conn.GetState() == connectivity.Ready }, 5*time.Second, time.Millisecond)
+	}
+
+	// The calls only go to the connections that are ready.
+	p.conns[0].conn.Close()
+	for range 10 {
+		_, err := p.Export(context.Background(), emptyRequest())
+		require.NoError(t, err)
+	}
+	sent, accepted, _ := p.conns[1].requests.load()
+	assert.Equal(t, int64(10), sent)
+	assert.Equal(t, int64(10), accepted)
+}
+
+func TestPool_Reconnects(t *testing.T) {
+	// The server closes the connections after their maximum age, and the client reopens them.
+	address, _, _ := startMetricsServer(t, grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: 50 * time.Millisecond,
+	}))
+	p, err := newPool([]string{address}, 1, balancingRoundRobin, dialInsecure)
+	require.NoError(t, err)
+	defer p.close()
+
+	c := p.conns[0]
+	require.Eventually(t, func() bool {
+		p.Export(context.Background(), emptyRequest())
+		return c.reconnects() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Positive(t, atomic.LoadInt64(&c.disconnects))
+}
+
+func TestPool_PickFirst_Reconnects(t *testing.T) {
+	first, firstServer, _ := startMetricsServer(t, grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: 50 * time.Millisecond,
+	}))
+	second, secondServer, _ := startMetricsServer(t)
+	p, err := newPool([]string{first, second}, 1, balancingPickFirst, dialInsecure)
+	require.NoError(t, err)
+	defer p.close()
+
+	// The calls go back to the first target once its connection is reopened.
+	c := p.targets[0].conns[0]
+	require.Eventually(t, func() bool {
+		p.Export(context.Background(), emptyRequest())
+		return c.reconnects() > 1
+	}, 5*time.Second, time.Millisecond)
+	before := firstServer.calls.Load()
+	require.Eventually(t, func() bool {
+		p.Export(context.Background(), emptyRequest())
+		return firstServer.calls.Load() > before
+	}, 5*time.Second, time.Millisecond)
+	assert.Greater(t, firstServer.calls.Load(), secondServer.calls.Load())
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reportedQuantiles are the latency percentiles reported, besides the minimum, mean and maximum.
+var reportedQuantiles = []struct {
+	name string
+	q    float64
+}{
+	{"p50", 0.5},
+	{"p90", 0.9},
+	{"p95", 0.95},
+	{"p99", 0.99},
+	{"p99.9", 0.999},
+}
+
+// statusCodeCounts counts the Export calls by gRPC status code. It is safe for concurrent use.
+type statusCodeCounts struct {
+	mutex  sync.Mutex
+	counts map[codes.Code]int64
+}
+
+// add counts n calls with a status code.
+func (c *statusCodeCounts) add(code codes.Code, n int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[codes.Code]int64)
+	}
+	c.counts[code] += n
+}
+
+// sorted returns the status codes seen, by code.
+func (c *statusCodeCounts) sorted() []codes.Code {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var seen []codes.Code
+	for code := range c.counts {
+		seen = append(seen, code)
+	}
+	slices.SortFunc(seen, func(a, b codes.Code) int { return cmp.Compare(a, b) })
+	return seen
+}
+
+func (c *statusCodeCounts) get(code codes.Code) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.counts[code]
+}
+
+// writeCounts writes counts of requests or data points by outcome.
+func writeCounts(w io.Writer, name string, c *counts) {
+	sent, accepted, rejected := c.load()
+	fmt.Fprintf(w, "%s Sent: %d\n", name, sent)
+	fmt.Fprintf(w, "%s Accepted: %d\n", name, accepted)
+	fmt.Fprintf(w, "%s Rejected: %d\n", name, rejected)
+}
+
+// writeLatencies writes the latency statistics of a histogram, one per line.
+func writeLatencies(w io.Writer, h *histogram) {
+	count, mean, minimum, maximum := h.snapshot()
+	fmt.Fprintf(w, "  count: %d\n", count)
+	fmt.Fprintf(w, "  min: %v\n", minimum)
+	fmt.Fprintf(w, "  mean: %v\n", mean)
+	for _, quantile := range reportedQuantiles {
+		fmt.Fprintf(w, "  %s: %v\n", quantile.name, h.quantile(quantile.q))
+	}
+	fmt.Fprintf(w, "  max: %v\n", maximum)
+}
+
+// formatLatencies returns the latency statistics of a histogram on a single line.
+func formatLatencies(h *histogram) string {
+	_, mean, minimum, maximum := h.snapshot()
+	fields := []string{fmt.Sprintf("min=%v", minimum), fmt.Sprintf("mean=%v", mean)}
+	for _, quantile := range reportedQuantiles {
+		fields = append(fields, fmt.Sprintf("%s=%v", quantile.name, h.quantile(quantile.q)))
+	}
+	fields = append(fields, fmt.Sprintf("max=%v", maximum))
+	return strings.Join(fields, " ")
+}
+
+// writeStatusCodes writes the number of Export calls by gRPC status code.
+func writeStatusCodes(w io.Writer, c *statusCodeCounts) {
+	for _, code := range c.sorted() {
+		fmt.Fprintf(w, "  %s: %d\n", code, c.get(code))
+	}
+}
+
+// writeStages writes the statistics of the stages of a scenario.
+func writeStages(w io.Writer, stages []*stageStats) {
+	for i, stats := range stages {
+		elapsed := stats.duration()
+		fmt.Fprintf(w, "Stage %d %q (%s), ran for %v:\n", i+1, stats.stage.Name, stats.stage, elapsed.Round(time.Millisecond))
+		sent, accepted, rejected := stats.requests.load()
+		fmt.Fprintf(w, "  Requests: %d sent, %d accepted, %d rejected, %.1f requests/s\n", sent, accepted, rejected, float64(sent)/elapsed.Seconds())
+		sent, accepted, rejected = stats.dataPoints.load()
+		fmt.Fprintf(w, "  Data Points: %d sent, %d accepted, %d rejected\n", sent, accepted, rejected)
+		if stats.stage.Rate > 0 {
+			fmt.Fprintf(w, "  Late Requests: %d\n", atomic.LoadInt64(&stats.late))
+		}
+		fmt.Fprintf(w, "  Latency: %s\n", formatLatencies(stats.latencies))
+	}
+}
+
+// writeTargets writes the statistics of the calls made to each target of a pool, and on each of
+// its connections. The latencies are those of the calls, from when they were made.
+func writeTargets(w io.Writer, p *pool) {
+	fmt.Fprintf(w, "Targets (%s balancing):\n", p.policy)
+	for _, t := range p.targets {
+		var requests counts
+		var disconnects, reconnects int64
+		targetLatencies := newHistogram()
+		for _, c := range t.conns {
+			sent, accepted, _ := c.requests.load()
+			requests.add(sent, accepted)
+			disconnects += atomic.LoadInt64(&c.disconnects)
+			reconnects += c.reconnects()
+			targetLatencies.merge(c.latencies.toProto())
+		}
+		sent, accepted, rejected := requests.load()
+		fmt.Fprintf(w, "  %s: %d sent, %d accepted, %d rejected, %d disconnects, %d reconnects, latency %s\n",
+			t.address, sent, accepted, rejected, disconnects, reconnects, formatLatencies(targetLatencies))
+		for _, c := range t.conns {
+			sent, accepted, rejected := c.requests.load()
+			fmt.Fprintf(w, "    Connection %d: %d sent, %d accepted, %d rejected, %d disconnects, %d reconnects, latency %s\n",
+				c.index+1, sent, accepted, rejected, atomic.LoadInt64(&c.disconnects), c.reconnects(), formatLatencies(c.latencies))
+		}
+	}
+}
+
+// reportPeriodically logs an interim report every interval until the context is done, for long
+// runs to be followed while they go on. The latencies of an interim report are those of the calls
+// completed during the interval.
+func reportPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	var lastRequests int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			requests, _, rejected := requestCounts.load()
+			dataPoints, _, rejectedDataPoints := dataPointCounts.load()
+			rate := float64(requests-lastRequests) / now.Sub(last).Seconds()
+			log.Printf("Interim report: %d requests, %d rejected, %d partial successes, %d data points, %d rejected, %.1f requests/s, latency %s",
+				requests, rejected, atomic.LoadInt64(&partialSuccessResponses), dataPoints, rejectedDataPoints,
+				rate, formatLatencies(intervalLatencies.drain()))
+			last, lastRequests = now, requests
+		}
+	}
+}
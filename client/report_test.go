@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestWriteStatusCodes(t *testing.T) {
+	var counts statusCodeCounts
+	for _, code := range []codes.Code{codes.Unavailable, codes.OK, codes.ResourceExhausted, codes.OK} {
+		counts.add(code, 1)
+	}
+
+	var buf bytes.Buffer
+	writeStatusCodes(&buf, &counts)
+	assert.Equal(t, "  OK: 2\n  ResourceExhausted: 1\n  Unavailable: 1\n", buf.String())
+}
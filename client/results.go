@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Formats of the results written with -output.
+const (
+	outputJSON = "json"
+	outputCSV  = "csv"
+)
+
+// results are the machine-readable results of a run, written with -output and read by compare.
+type results struct {
+	StartTime               time.Time        `json:"start_time"`
+	Parameters              runParameters    `json:"parameters"`
+	Server                  serverInfo       `json:"server"`
+	Total                   stageResult      `json:"total"`
+	StatusCodes             map[string]int64 `json:"status_codes"`
+	PartialSuccessResponses int64            `json:"partial_success_responses"`
+	Stages                  []stageResult    `json:"stages,omitempty"`
+	Aborted                 string           `json:"aborted,omitempty"`
+}
+
+// runParameters are the parameters of a run.
+type runParameters struct {
+	Filename        string   `json:"filename,omitempty"`
+	Workload        *profile `json:"workload,omitempty"`
+	Scenario        string   `json:"scenario,omitempty"`
+	DurationSeconds int      `json:"duration_seconds"`
+	Concurrency     int      `json:"concurrency"`
+	Rate            float64  `json:"rate,omitempty"`
+	Arrival         string   `json:"arrival"`
+	Seed            uint64   `json:"seed"`
+	Workers         int      `json:"workers,omitempty"` // Workers of a distributed run
+	Targets         []string `json:"targets"`
+	Connections     int      `json:"connections"` // Connections to each target
+	Balancing       string   `json:"balancing"`
+}
+
+// serverInfo is the version of the server, as returned by getVersion.
+type serverInfo struct {
+	Version        string `json:"version"`
+	GitCommitSha   string `json:"git_commit_sha"`
+	BuildTimestamp int64  `json:"build_timestamp"`
+}
+
+// stageResult are the statistics of a stage, or of the whole run.
+type stageResult struct {
+	Name              string        `json:"name"`
+	Target            string        `json:"target,omitempty"`
+	ElapsedSeconds    float64       `json:"elapsed_seconds"`
+	Requests          countResult   `json:"requests"`
+	DataPoints        countResult   `json:"data_points"`
+	RequestsPerSecond float64       `json:"requests_per_second"`
+	LateRequests      int64         `json:"late_requests"`
+	Latency           latencyResult `json:"latency"`
+}
+
+type countResult struct {
+	Sent     int64 `json:"sent"`
+	Accepted int64 `json:"accepted"`
+	Rejected int64 `json:"rejected"`
+}
+
+// latencyResult are latency statistics, in milliseconds.
+type latencyResult struct {
+	Count  int64   `json:"count"`
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	P999Ms float64 `json:"p99_9_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// errorRate returns the fraction of failed calls.
+func (r stageResult) errorRate() float64 {
+	if r.Requests.Sent == 0 {
+		return 0
+	}
+	return float64(r.Requests.Rejected) / float64(r.Requests.Sent)
+}
+
+func newCountResult(c *counts) countResult {
+	sent, accepted, rejected := c.load()
+	return countResult{Sent: sent, Accepted: accepted, Rejected: rejected}
+}
+
+func newLatencyResult(h *histogram) latencyResult {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	count, mean, minimum, maximum := h.snapshot()
+	return latencyResult{
+		Count:  count,
+		MinMs:  ms(minimum),
+		MeanMs: ms(mean),
+		P50Ms:  ms(h.quantile(0.5)),
+		P90Ms:  ms(h.quantile(0.9)),
+		P95Ms:  ms(h.quantile(0.95)),
+		P99Ms:  ms(h.quantile(0.99)),
+		P999Ms: ms(h.quantile(0.999)),
+		MaxMs:  ms(maximum),
+	}
+}
+
+func newStageResult(stats *stageStats) stageResult {
+	result := stageResult{
+		Name:           stats.stage.Name,
+		Target:         stats.stage.String(),
+		ElapsedSeconds: stats.duration().Seconds(),
+		Requests:       newCountResult(&stats.requests),
+		DataPoints:     newCountResult(&stats.dataPoints),
+		LateRequests:   stats.late,
+		Latency:        newLatencyResult(stats.latencies),
+	}
+	if result.ElapsedSeconds > 0 {
+		result.RequestsPerSecond = float64(result.Requests.Sent) / result.ElapsedSeconds
+	}
+	return result
+}
+
+// newResults collects the results of a run from the totals and the statistics of its stages.
+func newResults(start time.Time, parameters runParameters, elapsed time.Duration, stages []*stageStats, aborted error) results {
+	totalStats := &stageStats{
+		stage:      stage{Name: "total"},
+		requests:   requestCounts,
+		dataPoints: dataPointCounts,
+		latencies:  latencies,
+		late:       lateRequests,
+	}
+	totalStats.elapsed.Store(int64(elapsed))
+	total := newStageResult(totalStats)
+	total.Target = ""
+
+	r := results{
+		StartTime:  start,
+		Parameters: parameters,
+		Server: serverInfo{
+			Version:        serverVersion,
+			GitCommitSha:   gitCommitSha,
+			BuildTimestamp: buildTimeStamp,
+		},
+		Total:                   total,
+		StatusCodes:             make(map[string]int64),
+		PartialSuccessResponses: partialSuccessResponses,
+	}
+	for _, code := range statusCodes.sorted() {
+		r.StatusCodes[code.String()] = statusCodes.get(code)
+	}
+	for _, stats := range stages {
+		r.Stages = append(r.Stages, newStageResult(stats))
+	}
+	if aborted != nil {
+		r.Aborted = aborted.Error()
+	}
+	return r
+}
+
+// outputFormat returns the format of the results written to the path: the format if set, or the
+// one of the extension of the path, JSON by default.
+func outputFormat(path, format string) (string, error) {
+	if format == "" {
+		format = outputJSON
+		if filepath.Ext(path) == ".csv" {
+			format = outputCSV
+		}
+	}
+	if format != outputJSON && format != outputCSV {
+		return "", fmt.Errorf("unknown output format %q, expected %s or %s", format, outputJSON, outputCSV)
+	}
+	return format, nil
+}
+
+// writeResultsFile writes the results to the path in the format.
+func writeResultsFile(path, format string, r results) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == outputCSV {
+		err = writeResultsCSV(f, r)
+	} else {
+		err = writeResultsJSON(f, r)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeResultsJSON(w io.Writer, r results) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func readResultsFile(path string) (results, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return results{}, err
+	}
+	defer f.Close()
+	var r results
+	if err := json.NewDecoder(f).Decode(&r); err != nil {
+		return results{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+var csvHeader = []string{
+	"start_time", "server_version", "server_git_commit_sha", "seed", "concurrency", "rate",
+	"scope", "name", "target", "elapsed_seconds", "requests_per_second",
+	"requests_sent", "requests_accepted", "requests_rejected",
+	"data_points_sent", "data_points_accepted", "data_points_rejected", "late_requests",
+	"latency_count", "latency_min_ms", "latency_mean_ms", "latency_p50_ms", "latency_p90_ms",
+	"latency_p95_ms", "latency_p99_ms", "latency_p99_9_ms", "latency_max_ms",
+}
+
+// writeResultsCSV writes a row for the whole run, then a row per stage. Every row repeats the
+// parameters identifying the run, so the results of several runs can be concatenated.
+func writeResultsCSV(w io.Writer, r results) error {
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeader)
+	row := func(scope string, s stageResult) []string {
+		f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+		i := func(v int64) string { return strconv.FormatInt(v, 10) }
+		return []string{
+			r.StartTime.Format(time.RFC3339), r.Server.Version, r.Server.GitCommitSha,
+			strconv.FormatUint(r.Parameters.Seed, 10), strconv.Itoa(r.Parameters.Concurrency), f(r.Parameters.Rate),
+			scope, s.Name, s.Target, f(s.ElapsedSeconds), f(s.RequestsPerSecond),
+			i(s.Requests.Sent), i(s.Requests.Accepted), i(s.Requests.Rejected),
+			i(s.DataPoints.Sent), i(s.DataPoints.Accepted), i(s.DataPoints.Rejected), i(s.LateRequests),
+			i(s.Latency.Count), f(s.Latency.MinMs), f(s.Latency.MeanMs), f(s.Latency.P50Ms), f(s.Latency.P90Ms),
+			f(s.Latency.P95Ms), f(s.Latency.P99Ms), f(s.Latency.P999Ms), f(s.Latency.MaxMs),
+		}
+	}
+	writer.Write(row("total", r.Total))
+	for _, s := range r.Stages {
+		writer.Write(row("stage", s))
+	}
+	writer.Flush()
+	return writer.Error()
+}
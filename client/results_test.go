@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testResults() results {
+	stats := &stageStats{
+		stage:     stage{Name: "hold", Duration: time.Minute, Concurrency: 8, Ramp: rampStep},
+		latencies: newHistogram(),
+	}
+	stats.elapsed.Store(int64(2 * time.Second))
+	stats.requests.add(100, 98)
+	stats.dataPoints.add(1000, 950)
+	for i := 1; i <= 100; i++ {
+		stats.latencies.record(time.Duration(i) * time.Millisecond)
+	}
+	stage := newStageResult(stats)
+	total := stage
+	total.Name, total.Target = "total", ""
+	return results{
+		StartTime:   time.Date(2024, 5, 18, 22, 0, 0, 0, time.UTC),
+		Parameters:  runParameters{Concurrency: 8, Arrival: arrivalFixed, Seed: 42, Workload: &defaultProfile},
+		Server:      serverInfo{Version: "v1.2.0", GitCommitSha: "abc123"},
+		Total:       total,
+		StatusCodes: map[string]int64{"OK": 98, "Unavailable": 2},
+		Stages:      []stageResult{stage},
+	}
+}
+
+func TestNewStageResult(t *testing.T) {
+	r := testResults().Stages[0]
+	assert.Equal(t, "hold", r.Name)
+	assert.Equal(t, 2.0, r.ElapsedSeconds)
+	assert.Equal(t, 50.0, r.RequestsPerSecond)
+	assert.Equal(t, countResult{Sent: 100, Accepted: 98, Rejected: 2}, r.Requests)
+	assert.Equal(t, countResult{Sent: 1000, Accepted: 950, Rejected: 50}, r.DataPoints)
+	assert.Equal(t, 0.02, r.errorRate())
+	assert.Equal(t, int64(100), r.Latency.Count)
+	assert.Equal(t, 1.0, r.Latency.MinMs)
+	assert.InDelta(t, 99, r.Latency.P99Ms, 1)
+	assert.Equal(t, 100.0, r.Latency.MaxMs)
+}
+
+func TestResultsFile_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	want := testResults()
+	require.NoError(t, writeResultsFile(path, outputJSON, want))
+
+	got, err := readResultsFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsCSV(&buf, testResults()))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, csvHeader, rows[0])
+	column := func(row []string, name string) string {
+		for i, header := range csvHeader {
+			if header == name {
+				return row[i]
+			}
+		}
+		t.Fatalf("no column %s", name)
+		return ""
+	}
+	assert.Equal(t, "total", column(rows[1], "scope"))
+	assert.Equal(t, "stage", column(rows[2], "scope"))
+	assert.Equal(t, "hold", column(rows[2], "name"))
+	assert.Equal(t, "abc123", column(rows[2], "server_git_commit_sha"))
+	assert.Equal(t, "42", column(rows[2], "seed"))
+	assert.Equal(t, "98", column(rows[2], "requests_accepted"))
+	assert.Equal(t, "100", column(rows[2], "latency_max_ms"))
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		path, format, want string
+		wantErr            bool
+	}{
+		{"results.json", "", outputJSON, false},
+		{"results.csv", "", outputCSV, false},
+		{"results", "", outputJSON, false},
+		{"results.txt", outputCSV, outputCSV, false},
+		{"results.json", "xml", "", true},
+	}
+	for _, tt := range tests {
+		got, err := outputFormat(tt.path, tt.format)
+		if tt.wantErr {
+			assert.Error(t, err, tt.path)
+			continue
+		}
+		assert.NoError(t, err, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+}
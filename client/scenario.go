@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Ramps of a stage from the target of the previous stage.
+const (
+	rampStep   = "step"   // The target is applied at once
+	rampLinear = "linear" // The target is reached linearly over the duration of the stage
+)
+
+// rampInterval is how often the concurrency of a linear ramp is adjusted.
+const rampInterval = 100 * time.Millisecond
+
+// abortCheckInterval is how often the abort thresholds are checked.
+const abortCheckInterval = time.Second
+
+// errAborted is the cause of the end of a run stopped by an abort threshold.
+var errAborted = errors.New("run aborted")
+
+// stage is a part of a run with a target concurrency, the number of closed-loop workers, or a
+// target rate of requests per second.
+type stage struct {
+	Name        string        `mapstructure:"name"`
+	Duration    time.Duration `mapstructure:"duration"` // 0 lasts until the end of the run
+	Concurrency int           `mapstructure:"concurrency"`
+	Rate        float64       `mapstructure:"rate"`
+	Ramp        string        `mapstructure:"ramp"`
+}
+
+func (s stage) String() string {
+	target := fmt.Sprintf("concurrency %d", s.Concurrency)
+	if s.Rate > 0 {
+		target = fmt.Sprintf("rate %.1f requests/s", s.Rate)
+	}
+	duration := "until the end of the run"
+	if s.Duration > 0 {
+		duration = s.Duration.String()
+	}
+	return fmt.Sprintf("%s, %s, %s ramp", duration, target, s.Ramp)
+}
+
+// abortThresholds stop a run early when the calls of the current stage exceed them.
+type abortThresholds struct {
+	MaxErrorRate float64       `mapstructure:"max_error_rate"` // Fraction of failed calls, 0 disables
+	MaxP99       time.Duration `mapstructure:"max_p99"`        // 0 disables
+	MinRequests  int64         `mapstructure:"min_requests"`   // Calls of the stage before checking
+}
+
+// scenario is a sequence of stages, read from a YAML file.
+type scenario struct {
+	Stages []stage         `mapstructure:"stages"`
+	Abort  abortThresholds `mapstructure:"abort"`
+}
+
+func loadScenario(path string) (scenario, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetDefault("abort.min_requests", 100)
+	if err := v.ReadInConfig(); err != nil {
+		return scenario{}, err
+	}
+	var s scenario
+	if err := v.Unmarshal(&s); err != nil {
+		return scenario{}, err
+	}
+	for i := range s.Stages {
+		if s.Stages[i].Name == "" {
+			s.Stages[i].Name = fmt.Sprintf("stage %d", i+1)
+		}
+		if s.Stages[i].Ramp == "" {
+			s.Stages[i].Ramp = rampStep
+		}
+	}
+	return s, nil
+}
+
+func (s scenario) validate() error {
+	if len(s.Stages) == 0 {
+		return fmt.Errorf("at least one stage is required")
+	}
+	for _, st := range s.Stages {
+		switch {
+		case st.Duration <= 0:
+			return fmt.Errorf("stage %q: the duration must be positive", st.Name)
+		case (st.Concurrency > 0) == (st.Rate > 0):
+			return fmt.Errorf("stage %q: exactly one of concurrency and rate must be set", st.Name)
+		case st.Concurrency < 0 || st.Rate < 0:
+			return fmt.Errorf("stage %q: the target must not be negative", st.Name)
+		case st.Ramp != rampStep && st.Ramp != rampLinear:
+			return fmt.Errorf("stage %q: unknown ramp %q, expected %s or %s", st.Name, st.Ramp, rampStep, rampLinear)
+		}
+	}
+	if s.Abort.MaxErrorRate < 0 || s.Abort.MaxErrorRate > 1 {
+		return fmt.Errorf("the maximum error rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// check returns an error wrapping errAborted if the calls of a stage exceed the thresholds.
+func (a abortThresholds) check(stats *stageStats) error {
+	sent, _, rejected := stats.requests.load()
+	if sent == 0 || sent < a.MinRequests {
+		return nil
+	}
+	if errorRate := float64(rejected) / float64(sent); a.MaxErrorRate > 0 && errorRate > a.MaxErrorRate {
+		return fmt.Errorf("%w: error rate %.2f%% of stage %q above %.2f%%", errAborted, errorRate*100, stats.stage.Name, a.MaxErrorRate*100)
+	}
+	if p99 := stats.latencies.quantile(0.99); a.MaxP99 > 0 && p99 > a.MaxP99 {
+		return fmt.Errorf("%w: p99 latency %v of stage %q above %v", errAborted, p99, stats.stage.Name, a.MaxP99)
+	}
+	return nil
+}
+
+// stageStats are the statistics of the calls sent during a stage.
+type stageStats struct {
+	stage      stage
+	start      time.Time
+	requests   counts
+	dataPoints counts
+	latencies  *histogram
+	late       int64        // Requests of an open-loop stage sent late or not completed, see lateThreshold
+	elapsed    atomic.Int64 // Nanoseconds the stage ran for, 0 while it runs
+}
+
+// duration returns how long the stage ran for, or has been running.
+func (s *stageStats) duration() time.Duration {
+	if elapsed := s.elapsed.Load(); elapsed > 0 {
+		return time.Duration(elapsed)
+	}
+	return time.Since(s.start)
+}
+
+// runScenario runs the stages in sequence until the last one ends or the context of the run is
+// done, and returns the statistics of the stages that ran. It stops the run with errAborted as
+// soon as the calls of a stage exceed the abort thresholds.
+func runScenario(ctx context.Context, stop context.CancelCauseFunc, r *runner, stages []stage, abort abortThresholds, arrival string, rng *rand.Rand) []*stageStats {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(abortCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if stats := r.stage.Load(); stats != nil {
+					if err := abort.check(stats); err != nil {
+						stop(err)
+					}
+				}
+			}
+		}
+	}()
+
+	var previous stage
+	for _, st := range stages {
+		stats := r.startStage(st)
+		stageCtx, cancel := ctx, context.CancelFunc(func() {})
+		if st.Duration > 0 {
+			stageCtx, cancel = context.WithTimeout(ctx, st.Duration)
+		}
+		if st.Rate > 0 {
+			r.setWorkers(0)
+			ramp := rateRamp{from: st.Rate, to: st.Rate}
+			if st.Ramp == rampLinear {
+				ramp = rateRamp{from: previous.Rate, to: st.Rate, duration: st.Duration}
+			}
+			r.openLoop(stageCtx, ramp, newArrivals(arrival, rng))
+		} else {
+			rampWorkers(stageCtx, r, previous.Concurrency, st)
+		}
+		stats.elapsed.Store(int64(max(time.Since(stats.start), 1)))
+		cancel()
+
+		// The calls of a stage shorter than the check interval are checked too.
+		if err := abort.check(stats); err != nil {
+			stop(err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		previous = st
+	}
+	return r.startedStages()
+}
+
+// rampWorkers runs the closed-loop workers of a stage until the stage context is done, going
+// from the concurrency of the previous stage to the target of the stage.
+func rampWorkers(stageCtx context.Context, r *runner, from int, st stage) {
+	if st.Ramp != rampLinear {
+		r.setWorkers(st.Concurrency)
+		<-stageCtx.Done()
+		return
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(rampInterval)
+	defer ticker.Stop()
+	for {
+		progress := min(time.Since(start).Seconds()/st.Duration.Seconds(), 1)
+		r.setWorkers(max(from+int(float64(st.Concurrency-from)*progress+0.5), 1))
+		select {
+		case <-stageCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeMetricsClient answers Export calls after a delay, failing them while fail is set.
+type fakeMetricsClient struct {
+	delay time.Duration
+	fail  atomic.Bool
+	calls atomic.Int64
+}
+
+func (c *fakeMetricsClient) Export(ctx context.Context, _ *pb.ExportMetricsServiceRequest, _ ...grpc.CallOption) (*pb.ExportMetricsServiceResponse, error) {
+	c.calls.Add(1)
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if c.fail.Load() {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	return &pb.ExportMetricsServiceResponse{}, nil
+}
+
+func emptyRequest() *pb.ExportMetricsServiceRequest {
+	return &pb.ExportMetricsServiceRequest{}
+}
+
+func TestLoadScenario(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	content := `
+stages:
+  - name: ramp-up
+    duration: 30s
+    concurrency: 50
+    ramp: linear
+  - duration: 1m
+    rate: 1000
+abort:
+  max_error_rate: 0.05
+  max_p99: 50ms
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	s, err := loadScenario(path)
+	require.NoError(t, err)
+	assert.Equal(t, []stage{
+		{Name: "ramp-up", Duration: 30 * time.Second, Concurrency: 50, Ramp: rampLinear},
+		{Name: "stage 2", Duration: time.Minute, Rate: 1000, Ramp: rampStep},
+	}, s.Stages)
+	assert.Equal(t, abortThresholds{MaxErrorRate: 0.05, MaxP99: 50 * time.Millisecond, MinRequests: 100}, s.Abort)
+	assert.NoError(t, s.validate())
+
+	s, err = loadScenario("scenarios/example.yaml")
+	require.NoError(t, err)
+	assert.NoError(t, s.validate())
+}
+
+func TestScenario_Validate(t *testing.T) {
+	valid := stage{Name: "s", Duration: time.Second, Concurrency: 1, Ramp: rampStep}
+	tests := []struct {
+		name   string
+		modify func(s *scenario)
+	}{
+		{"no stages", func(s *scenario) { s.Stages = nil }},
+		{"no duration", func(s *scenario) { s.Stages[0].Duration = 0 }},
+		{"no target", func(s *scenario) { s.Stages[0].Concurrency = 0 }},
+		{"both targets", func(s *scenario) { s.Stages[0].Rate = 10 }},
+		{"unknown ramp", func(s *scenario) { s.Stages[0].Ramp = "exponential" }},
+		{"error rate above 1", func(s *scenario) { s.Abort.MaxErrorRate = 5 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := scenario{Stages: []stage{valid}}
+			require.NoError(t, s.validate())
+			tt.modify(&s)
+			assert.Error(t, s.validate())
+		})
+	}
+}
+
+func TestRateRamp_Offset(t *testing.T) {
+	tests := []struct {
+		name     string
+		ramp     rateRamp
+		position float64
+		want     time.Duration
+		ok       bool
+	}{
+		{"constant", rateRamp{from: 100, to: 100}, 50, 500 * time.Millisecond, true},
+		{"constant forever", rateRamp{from: 100, to: 100}, 1e6, 10000 * time.Second, true},
+		{"constant past the end", rateRamp{from: 100, to: 100, duration: time.Second}, 150, 0, false},
+		// From 0 to 100 requests/s over 10s, 50*x²/10 requests are expected by x seconds.
+		{"ramp up from 0, first", rateRamp{from: 0, to: 100, duration: 10 * time.Second}, 0, 0, true},
+		{"ramp up from 0", rateRamp{from: 0, to: 100, duration: 10 * time.Second}, 125, 5 * time.Second, true},
+		{"ramp up, last", rateRamp{from: 0, to: 100, duration: 10 * time.Second}, 500, 10 * time.Second, true},
+		// From 100 to 0 requests/s over 10s, 500 requests in total.
+		{"ramp down", rateRamp{from: 100, to: 0, duration: 10 * time.Second}, 375, 5 * time.Second, true},
+		{"ramp down past the end", rateRamp{from: 100, to: 0, duration: 10 * time.Second}, 501, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.ramp.offset(tt.position)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, float64(time.Millisecond))
+		})
+	}
+}
+
+func TestAbortThresholds_Check(t *testing.T) {
+	stats := &stageStats{stage: stage{Name: "hold"}, latencies: newHistogram()}
+	for i := 0; i < 100; i++ {
+		stats.latencies.record(10 * time.Millisecond)
+	}
+	stats.requests.add(90, 85)
+
+	thresholds := abortThresholds{MaxErrorRate: 0.05, MaxP99: 50 * time.Millisecond, MinRequests: 100}
+	assert.NoError(t, thresholds.check(stats), "below the minimum number of requests")
+
+	stats.requests.add(10, 10)
+	assert.NoError(t, thresholds.check(stats), "5% of errors")
+
+	stats.requests.add(1, 0)
+	assert.ErrorIs(t, thresholds.check(stats), errAborted)
+
+	thresholds.MaxErrorRate = 0
+	assert.NoError(t, thresholds.check(stats))
+	thresholds.MaxP99 = 5 * time.Millisecond
+	assert.ErrorIs(t, thresholds.check(stats), errAborted)
+}
+
+func TestRunScenario(t *testing.T) {
+	client := &fakeMetricsClient{delay: time.Millisecond}
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	r := newRunner(ctx, client, emptyRequest, 4)
+
+	stages := []stage{
+		{Name: "ramp", Duration: 200 * time.Millisecond, Concurrency: 4, Ramp: rampLinear},
+		{Name: "rate", Duration: 200 * time.Millisecond, Rate: 200, Ramp: rampStep},
+		{Name: "hold", Duration: 100 * time.Millisecond, Concurrency: 2, Ramp: rampStep},
+	}
+	stats := runScenario(ctx, abort, r, stages, abortThresholds{}, arrivalFixed, rand.New(rand.NewPCG(1, 2)))
+	r.wait()
+
+	require.Len(t, stats, 3)
+	assert.NoError(t, context.Cause(ctx))
+	var total int64
+	for _, s := range stats {
+		sent, accepted, _ := s.requests.load()
+		assert.Positive(t, sent, s.stage.Name)
+		assert.Equal(t, sent, accepted, s.stage.Name)
+		total += sent
+	}
+	// 200 requests/s over 200ms.
+	sent, _, _ := stats[1].requests.load()
+	assert.InDelta(t, 40, sent, 4)
+	assert.Equal(t, client.calls.Load(), total)
+}
+
+func TestRunScenario_SlowServer(t *testing.T) {
+	// A single call in flight, and a server 10 times too slow for the rate.
+	client := &fakeMetricsClient{delay: 100 * time.Millisecond}
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	r := newRunner(ctx, client, emptyRequest, 1)
+
+	stages := []stage{{Name: "rate", Duration: 200 * time.Millisecond, Rate: 100, Ramp: rampStep}}
+	stats := runScenario(ctx, abort, r, stages, abortThresholds{}, arrivalFixed, rand.New(rand.NewPCG(1, 2)))
+	abort(nil)
+	r.wait()
+
+	// The requests due but not sent, and the call cancelled by the end of the run, are late and
+	// keep their latency.
+	require.Len(t, stats, 1)
+	sent, _, _ := stats[0].requests.load()
+	assert.LessOrEqual(t, sent, int64(2))
+	assert.InDelta(t, 20-sent, atomic.LoadInt64(&stats[0].late), 2)
+	count, _, _, maximum := stats[0].latencies.snapshot()
+	assert.InDelta(t, 20, count, 2)
+	assert.GreaterOrEqual(t, maximum, 150*time.Millisecond)
+}
+
+func TestRunScenario_Abort(t *testing.T) {
+	client := &fakeMetricsClient{delay: time.Millisecond}
+	client.fail.Store(true)
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	r := newRunner(ctx, client, emptyRequest, 1)
+
+	stages := []stage{
+		{Name: "failing", Duration: 50 * time.Millisecond, Concurrency: 2, Ramp: rampStep},
+		{Name: "never run", Duration: time.Minute, Concurrency: 2, Ramp: rampStep},
+	}
+	thresholds := abortThresholds{MaxErrorRate: 0.05, MinRequests: 1}
+	start := time.Now()
+	stats := runScenario(ctx, abort, r, stages, thresholds, arrivalFixed, rand.New(rand.NewPCG(1, 2)))
+	r.wait()
+
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Len(t, stats, 1)
+	assert.True(t, errors.Is(context.Cause(ctx), errAborted))
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lateThreshold is how long after its intended time a request of an open-loop stage may be sent
+// before it is counted as late. The requests due but not sent before the end of their stage, and
+// those not completed before the end of the run, are counted as late too.
+const lateThreshold = time.Millisecond
+
+// Arrival processes of an open-loop stage.
+const (
+	arrivalFixed   = "fixed"   // Requests are evenly spaced
+	arrivalPoisson = "poisson" // Requests arrive independently, with exponentially distributed gaps
+)
+
+// runner makes the Export calls of a run, with the requests returned by next. The calls are made
+// with the context of the run, so the ones in flight at the end of the run are cancelled rather
+// than waited for, whereas the ones in flight at the end of a stage complete normally.
+type runner struct {
+	ctx    context.Context
+	client pb.MetricsServiceClient
+	next   func() *pb.ExportMetricsServiceRequest
+
+	stage   atomic.Pointer[stageStats] // Statistics of the current stage
+	mutex   sync.Mutex                 // Guards stages
+	stages  []*stageStats              // Statistics of the stages started so far
+	calls   sync.WaitGroup             // Goroutines making calls
+	workers []chan struct{}            // Closed to stop each closed-loop worker
+	slots   chan struct{}              // Calls in flight of the open-loop stages
+}
+
+func newRunner(ctx context.Context, client pb.MetricsServiceClient, next func() *pb.ExportMetricsServiceRequest, maxInFlight int) *runner {
+	return &runner{
+		ctx:    ctx,
+		client: client,
+		next:   next,
+		slots:  make(chan struct{}, maxInFlight),
+	}
+}
+
+// startStage makes a stage the current one, and returns its statistics.
+func (r *runner) startStage(st stage) *stageStats {
+	stats := &stageStats{stage: st, start: time.Now(), latencies: newHistogram()}
+	r.mutex.Lock()
+	r.stages = append(r.stages, stats)
+	r.mutex.Unlock()
+	r.stage.Store(stats)
+	return stats
+}
+
+// startedStages returns the statistics of the stages started so far, in order.
+func (r *runner) startedStages() []*stageStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.stages)
+}
+
+// setWorkers adjusts the number of closed-loop workers, each making one Export call at a time.
+// A stopped worker returns once its call completes.
+func (r *runner) setWorkers(n int) {
+	for len(r.workers) < n {
+		stop := make(chan struct{})
+		r.workers = append(r.workers, stop)
+		r.calls.Add(1)
+		go func() {
+			defer r.calls.Done()
+			r.worker(stop)
+		}()
+	}
+	for len(r.workers) > n {
+		close(r.workers[len(r.workers)-1])
+		r.workers = r.workers[:len(r.workers)-1]
+	}
+}
+
+// worker sends requests one after the other until it is stopped or the run ends.
+func (r *runner) worker(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		req := r.next()
+		if !r.send(req, time.Now(), r.stage.Load()) {
+			return
+		}
+	}
+}
+
+// wait stops the workers and waits for every call to complete.
+func (r *runner) wait() {
+	r.setWorkers(0)
+	r.calls.Wait()
+}
+
+// send makes an Export call and records its outcome in the stage it was sent in, with its latency
+// measured from start. It returns false once the run is over.
+func (r *runner) send(req *pb.ExportMetricsServiceRequest, start time.Time, stats *stageStats) bool {
+	if r.ctx.Err() != nil {
+		return false
+	}
+	resp, err := r.client.Export(r.ctx, req)
+	if r.ctx.Err() != nil {
+		// Interrupted by the end of the run, the call is not counted.
+		return false
+	}
+	latency := time.Since(start)
+	latencies.record(latency)
+	intervalLatencies.record(latency)
+	stats.latencies.record(latency)
+	recordResponse(stats, req, resp, err)
+	return true
+}
+
+// openLoop sends requests at their intended times until the stage context is done, whatever the
+// response time of the server, with at most as many calls in flight as the runner has slots.
+//
+// The latency of a request is measured from its intended time rather than from when it was
+// actually sent, so the time a request waits for a free slot while the server is slow is
+// accounted for instead of hidden (coordinated omission). Requests sent more than lateThreshold
+// after their intended time are counted as late, and so are the requests that are not sent or not
+// completed in time, whose latency up to then is recorded so that they stay in the slow tail.
+func (r *runner) openLoop(stageCtx context.Context, ramp rateRamp, arrivals *arrivals) {
+	stats := r.stage.Load()
+	start := time.Now()
+	for {
+		offset, ok := ramp.offset(arrivals.next())
+		if !ok {
+			// No more requests are due in the stage.
+			<-stageCtx.Done()
+			return
+		}
+		// The schedule does not drift when the runner falls behind: the overdue requests are
+		// sent as soon as slots are available.
+		intended := start.Add(offset)
+		if !sleepUntil(stageCtx, intended) {
+			r.dropOverdue(stageCtx, stats, ramp, arrivals, start, intended)
+			return
+		}
+		select {
+		case r.slots <- struct{}{}:
+		case <-stageCtx.Done():
+			r.dropOverdue(stageCtx, stats, ramp, arrivals, start, intended)
+			return
+		}
+		if time.Since(intended) > lateThreshold {
+			atomic.AddInt64(&lateRequests, 1)
+			atomic.AddInt64(&stats.late, 1)
+		}
+		r.calls.Add(1)
+		go func() {
+			defer r.calls.Done()
+			defer func() { <-r.slots }()
+			if !r.send(r.next(), intended, stats) {
+				// Cancelled by the end of the run.
+				recordUnfinished(stats, time.Since(intended))
+			}
+		}()
+	}
+}
+
+// dropOverdue accounts for the requests of an open-loop stage that were due before the end of the
+// stage but not sent, from the one intended at intended on.
+func (r *runner) dropOverdue(stageCtx context.Context, stats *stageStats, ramp rateRamp, arrivals *arrivals, start, intended time.Time) {
+	end := time.Now()
+	if deadline, ok := stageCtx.Deadline(); ok && deadline.Before(end) {
+		end = deadline
+	}
+	for !intended.After(end) {
+		recordUnfinished(stats, end.Sub(intended))
+		offset, ok := ramp.offset(arrivals.next())
+		if !ok {
+			return
+		}
+		intended = start.Add(offset)
+	}
+}
+
+// recordUnfinished counts a request of an open-loop stage that did not complete as late, and
+// records how long it waited, a lower bound of its latency.
+func recordUnfinished(stats *stageStats, latency time.Duration) {
+	atomic.AddInt64(&lateRequests, 1)
+	atomic.AddInt64(&stats.late, 1)
+	latencies.record(latency)
+	intervalLatencies.record(latency)
+	stats.latencies.record(latency)
+}
+
+// arrivals generates the positions of the requests of an open-loop stage, in number of requests
+// expected before them: 0, 1, 2... for fixed arrivals, and the points of a Poisson process of rate
+// 1 for Poisson arrivals.
+type arrivals struct {
+	poisson  bool
+	rng      *rand.Rand
+	position float64
+}
+
+func newArrivals(process string, rng *rand.Rand) *arrivals {
+	return &arrivals{poisson: process == arrivalPoisson, rng: rng}
+}
+
+// next returns the position of the next request.
+func (a *arrivals) next() float64 {
+	position := a.position
+	if a.poisson {
+		a.position += a.rng.ExpFloat64()
+	} else {
+		a.position++
+	}
+	return position
+}
+
+// rateRamp is a request rate, in requests per second, varying linearly from from to to over the
+// duration. A ramp without duration keeps the from rate forever.
+type rateRamp struct {
+	from, to float64
+	duration time.Duration
+}
+
+// offset returns when the request at a position is due, relative to the start of the ramp, or
+// false if it is not due before the end of the ramp.
+func (r rateRamp) offset(position float64) (time.Duration, bool) {
+	var slope float64
+	if r.duration > 0 {
+		slope = (r.to - r.from) / r.duration.Seconds()
+	}
+	// The number of requests expected by x seconds is from*x + slope*x²/2. This form of its
+	// root also holds when the rate is constant or starts at 0.
+	discriminant := r.from*r.from + 2*slope*position
+	if discriminant < 0 {
+		return 0, false
+	}
+	denominator := r.from + math.Sqrt(discriminant)
+	if denominator <= 0 {
+		return 0, position == 0
+	}
+	offset := time.Duration(2 * position / denominator * float64(time.Second))
+	if r.duration > 0 && offset > r.duration {
+		return 0, false
+	}
+	return offset, true
+}
+
+// sleepUntil waits until the time, and returns false if the context is done first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	if wait := time.Until(t); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
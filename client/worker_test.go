@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyClient answers Export calls after a delay, and records the peak number of calls in
+// flight and the calls that completed. While hold is set, the calls never complete.
+type concurrencyClient struct {
+	delay     time.Duration
+	hold      atomic.Bool
+	inFlight  atomic.Int64
+	peak      atomic.Int64
+	held      atomic.Int64
+	completed atomic.Int64
+}
+
+func (c *concurrencyClient) Export(ctx context.Context, _ *pb.ExportMetricsServiceRequest, _ ...grpc.CallOption) (*pb.ExportMetricsServiceResponse, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for peak := c.peak.Load(); n > peak && !c.peak.CompareAndSwap(peak, n); peak = c.peak.Load() {
+	}
+	var done <-chan time.Time
+	if c.hold.Load() {
+		c.held.Add(1)
+	} else {
+		done = time.After(c.delay)
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	c.completed.Add(1)
+	return &pb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestRunner_Workers(t *testing.T) {
+	client := &concurrencyClient{delay: 20 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newRunner(ctx, client, emptyRequest, 1)
+	stats := r.startStage(stage{Name: "closed", Concurrency: 4, Ramp: rampStep})
+
+	r.setWorkers(4)
+	require.Eventually(t, func() bool { return client.completed.Load() >= 20 }, 5*time.Second, time.Millisecond)
+	// The calls in flight at the end of the run are cancelled and not counted.
+	client.hold.Store(true)
+	require.Eventually(t, func() bool { return client.held.Load() == 4 }, 5*time.Second, time.Millisecond)
+	cancel()
+	r.wait()
+
+	assert.Equal(t, int64(4), client.peak.Load())
+	assert.Zero(t, client.inFlight.Load())
+	sent, accepted, _ := stats.requests.load()
+	assert.Equal(t, client.completed.Load(), sent)
+	assert.Equal(t, sent, accepted)
+	count, _, _, _ := stats.latencies.snapshot()
+	assert.Equal(t, sent, count)
+}
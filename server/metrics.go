@@ -1,28 +0,0 @@
-package main
-
-import (
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-var (
-	requestCount = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "grpc_request_count",
-			Help: "Total number of gRPC requests",
-		},
-		[]string{"method", "client", "code"},
-	)
-	requestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "grpc_request_duration_seconds",
-			Help:    "Duration of gRPC requests in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method"},
-	)
-)
-
-func init() {
-	// Register the metrics with Prometheus's default registry
-	prometheus.MustRegister(requestCount, requestDuration)
-}
@@ -0,0 +1,197 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	mathrand "math/rand/v2"
+	"time"
+)
+
+// requestIDHeader is the metadata key carrying the request ID, in requests and in response headers.
+const requestIDHeader = "x-request-id"
+
+// maxRequestIDLength bounds the length of a request ID supplied by a client.
+const maxRequestIDLength = 128
+
+// AccessLogConfig configures the access log entries written for every RPC.
+type AccessLogConfig struct {
+	Enabled           bool    `mapstructure:"enabled"`
+	Level             string  `mapstructure:"level"`
+	ErrorLevel        string  `mapstructure:"error_level"`
+	SuccessSampleRate float64 `mapstructure:"success_sample_rate"`
+	ErrorSampleRate   float64 `mapstructure:"error_sample_rate"`
+}
+
+// accessLog writes one log entry per RPC. Successful and failed RPCs are logged at their own level
+// and sampled independently, so errors can be kept in full while successes are thinned out.
+type accessLog struct {
+	logger            *zap.Logger
+	level             zapcore.Level
+	errorLevel        zapcore.Level
+	successSampleRate float64
+	errorSampleRate   float64
+}
+
+func newAccessLog(logger *zap.Logger, config AccessLogConfig) (*accessLog, error) {
+	a := &accessLog{
+		logger:            logger.Named("access"),
+		successSampleRate: config.SuccessSampleRate,
+		errorSampleRate:   config.ErrorSampleRate,
+	}
+	if err := a.level.UnmarshalText([]byte(config.Level)); err != nil {
+		return nil, fmt.Errorf("invalid access log level: %w", err)
+	}
+	if err := a.errorLevel.UnmarshalText([]byte(config.ErrorLevel)); err != nil {
+		return nil, fmt.Errorf("invalid access log error level: %w", err)
+	}
+	return a, nil
+}
+
+// write logs an RPC that finished with the given error, subject to sampling.
+func (a *accessLog) write(ctx context.Context, err error, fields ...zap.Field) {
+	code := status.Code(err)
+	level, rate := a.level, a.successSampleRate
+	if code != codes.OK {
+		level, rate = a.errorLevel, a.errorSampleRate
+	}
+	if rate < 1 && mathrand.Float64() >= rate {
+		return
+	}
+
+	fields = append(fields,
+		zap.String("request_id", requestIDFromContext(ctx)),
+		zap.String("identity", clientIdentity(ctx)),
+		zap.String("peer", peerAddress(ctx)),
+		zap.String("listener", ListenerFromContext(ctx)),
+		zap.Stringer("code", code),
+		zap.Error(err),
+	)
+	a.logger.Log(level, "Request finished", append(fields, traceFields(ctx)...)...)
+}
+
+// UnaryInterceptorRequestID returns a gRPC unary interceptor that assigns a request ID to every
+// call, whether or not the access log is enabled.
+//
+// The request ID is taken from the x-request-id metadata of the request when the client sent one,
+// and generated otherwise. It is returned to the client in the x-request-id response header and
+// stored in the context of the request, where requestIDFromContext retrieves it.
+func UnaryInterceptorRequestID(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := incomingRequestID(ctx)
+		ctx = withRequestID(ctx, requestID)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID)); err != nil {
+			logger.Warn("Failed to set request ID header", zap.Error(err))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptorRequestID is the streaming counterpart of UnaryInterceptorRequestID.
+func StreamInterceptorRequestID(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		requestID := incomingRequestID(ss.Context())
+		if err := ss.SetHeader(metadata.Pairs(requestIDHeader, requestID)); err != nil {
+			logger.Warn("Failed to set request ID header", zap.Error(err))
+		}
+		stream := newMonitoredServerStream(ss)
+		stream.ctx = withRequestID(ss.Context(), requestID)
+		return handler(srv, stream)
+	}
+}
+
+// UnaryInterceptorLogging returns a gRPC unary interceptor that writes an access log entry once
+// a call is handled, with the request ID assigned by UnaryInterceptorRequestID.
+func UnaryInterceptorLogging(a *accessLog) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+
+		fields := []zap.Field{
+			zap.String("method", info.FullMethod),
+			zap.Duration("duration", duration),
+		}
+		if msg, ok := req.(proto.Message); ok {
+			fields = append(fields, zap.Int("request_size", proto.Size(msg)))
+		}
+		if exportResp, ok := resp.(*pb.ExportMetricsServiceResponse); ok {
+			fields = append(fields, zap.Int64("rejected_data_points", exportResp.GetPartialSuccess().GetRejectedDataPoints()))
+		}
+		a.write(ctx, err, fields...)
+
+		return resp, err
+	}
+}
+
+// StreamInterceptorLogging is the streaming counterpart of UnaryInterceptorLogging. It writes one
+// access log entry per stream once it ends, which includes the number of messages received and
+// sent.
+func StreamInterceptorLogging(a *accessLog) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		stream := newMonitoredServerStream(ss)
+
+		start := time.Now()
+		err := handler(srv, stream)
+		duration := time.Since(start)
+
+		a.write(stream.ctx, err,
+			zap.String("method", info.FullMethod),
+			zap.Duration("duration", duration),
+			zap.Int64("messages_received", stream.received.Load()),
+			zap.Int64("messages_sent", stream.sent.Load()),
+		)
+
+		return err
+	}
+}
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+func withRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// requestIDFromContext returns the request ID assigned by the request ID interceptor, or an empty
+// string if there is none.
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// incomingRequestID returns the request ID sent by the client, or a new one if the client did not
+// send a usable ID.
+func incomingRequestID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDHeader); len(values) > 0 {
+		if id := values[0]; id != "" && len(id) <= maxRequestIDLength && isPrintableASCII(id) {
+			return id
+		}
+	}
+	return newRequestID()
+}
+
+// newRequestID generates a random 128-bit request ID.
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package metricsserver
+
+import (
+	"context"
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+// fakeTransportStream captures the response headers set by a unary handler.
+type fakeTransportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *fakeTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func newObservedAccessLog(t *testing.T, config AccessLogConfig) (*accessLog, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	a, err := newAccessLog(zap.New(core), config)
+	assert.NoError(t, err)
+	return a, logs
+}
+
+var testAccessLogConfig = AccessLogConfig{
+	Level:             "info",
+	ErrorLevel:        "warn",
+	SuccessSampleRate: 1,
+	ErrorSampleRate:   1,
+}
+
+func TestUnaryInterceptorLogging(t *testing.T) {
+	a, logs := newObservedAccessLog(t, testAccessLogConfig)
+	interceptor := grpcmiddleware.ChainUnaryServer(UnaryInterceptorRequestID(zap.NewNop()), UnaryInterceptorLogging(a))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Export"}
+
+	stream := &fakeTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(peerContext("10.0.0.1", 1), stream)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(requestIDHeader, "req-1"))
+
+	var handlerRequestID string
+	_, err := interceptor(ctx, &pb.ExportMetricsServiceRequest{}, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		handlerRequestID = requestIDFromContext(ctx)
+		return &pb.ExportMetricsServiceResponse{
+			PartialSuccess: &pb.ExportMetricsPartialSuccess{RejectedDataPoints: 3},
+		}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", handlerRequestID)
+	assert.Equal(t, []string{"req-1"}, stream.header.Get(requestIDHeader))
+
+	entries := logs.FilterMessage("Request finished").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		assert.Equal(t, "/test.Service/Export", fields["method"])
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "10.0.0.1:1", fields["peer"])
+		assert.Equal(t, "OK", fields["code"])
+		assert.Equal(t, int64(3), fields["rejected_data_points"])
+		assert.Contains(t, fields, "request_size")
+	}
+}
+
+func TestUnaryInterceptorRequestID_Generated(t *testing.T) {
+	interceptor := UnaryInterceptorRequestID(zap.NewNop())
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Export"}
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+	}{
+		{name: "Missing", md: metadata.MD{}},
+		{name: "Too Long", md: metadata.Pairs(requestIDHeader, string(make([]byte, maxRequestIDLength+1)))},
+		{name: "Not Printable", md: metadata.Pairs(requestIDHeader, "id\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &fakeTransportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			ctx = metadata.NewIncomingContext(ctx, tt.md)
+
+			_, err := interceptor(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				assert.Len(t, requestIDFromContext(ctx), 32)
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			assert.Len(t, stream.header.Get(requestIDHeader), 1)
+		})
+	}
+}
+
+func TestUnaryInterceptorLogging_Sampling(t *testing.T) {
+	a, logs := newObservedAccessLog(t, AccessLogConfig{
+		Level:             "info",
+		ErrorLevel:        "error",
+		SuccessSampleRate: 0,
+		ErrorSampleRate:   1,
+	})
+	interceptor := UnaryInterceptorLogging(a)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Export"}
+
+	for i := 0; i < 10; i++ {
+		interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+	}
+	_, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	})
+	assert.Error(t, err)
+
+	entries := logs.FilterMessage("Request finished").All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+		assert.Equal(t, "Unavailable", entries[0].ContextMap()["code"])
+	}
+}
+
+func TestStreamInterceptorLogging(t *testing.T) {
+	a, logs := newObservedAccessLog(t, testAccessLogConfig)
+	interceptor := grpcmiddleware.ChainStreamServer(StreamInterceptorRequestID(zap.NewNop()), StreamInterceptorLogging(a))
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+
+	ctx := metadata.NewIncomingContext(peerContext("10.0.0.1", 1), metadata.Pairs(requestIDHeader, "req-1"))
+	stream := &fakeServerStream{ctx: ctx, pending: 2}
+	err := interceptor(nil, stream, info, echoHandler)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"req-1"}, stream.header.Get(requestIDHeader))
+
+	entries := logs.FilterMessage("Request finished").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "/test.Service/Stream", fields["method"])
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, int64(2), fields["messages_received"])
+		assert.Equal(t, int64(2), fields["messages_sent"])
+	}
+}
+
+func TestNewAccessLog_InvalidLevel(t *testing.T) {
+	_, err := newAccessLog(zap.NewNop(), AccessLogConfig{Level: "loud", ErrorLevel: "warn"})
+	assert.Error(t, err)
+}
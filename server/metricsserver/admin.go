@@ -0,0 +1,179 @@
+package metricsserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// AdminConfig configures the administrative HTTP API.
+type AdminConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Address           string        `mapstructure:"address"`
+	AllowedIdentities []string      `mapstructure:"allowed_identities"`
+	MaxOverride       time.Duration `mapstructure:"max_override"`
+}
+
+// adminHandler serves the administrative HTTP API of the server.
+//
+// The API is only reachable over mutual TLS. Callers are identified by the common name of their
+// client certificate and, when an allow list is configured, must appear on it. Every request,
+// including rejected ones, is written to the audit log.
+type adminHandler struct {
+	mux         *http.ServeMux
+	levels      *LevelController
+	audit       *zap.Logger
+	allowed     map[string]struct{}
+	maxOverride time.Duration
+}
+
+// logLevelRequest is the body accepted by PUT /admin/loglevel.
+type logLevelRequest struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// logLevelResponse describes the current log level configuration.
+type logLevelResponse struct {
+	Base     zapcore.Level  `json:"base"`
+	Override *LevelOverride `json:"override,omitempty"`
+}
+
+func newAdminHandler(config AdminConfig, levels *LevelController) *adminHandler {
+	h := &adminHandler{
+		mux:         http.NewServeMux(),
+		levels:      levels,
+		audit:       levels.AuditLogger(),
+		allowed:     make(map[string]struct{}, len(config.AllowedIdentities)),
+		maxOverride: config.MaxOverride,
+	}
+	for _, identity := range config.AllowedIdentities {
+		h.allowed[identity] = struct{}{}
+	}
+
+	h.mux.HandleFunc("GET /admin/loglevel", h.getLogLevel)
+	h.mux.HandleFunc("PUT /admin/loglevel", h.setLogLevel)
+	h.mux.HandleFunc("DELETE /admin/loglevel", h.clearLogLevel)
+	h.mux.HandleFunc("GET /admin/debug/goroutines", h.goroutines)
+	h.mux.HandleFunc("GET /admin/debug/heap", h.heap)
+
+	return h
+}
+
+// ServeHTTP authenticates the caller before dispatching the request.
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	identity := identityFromTLS(r.TLS)
+	if identity == unknownIdentity {
+		h.auditLog(r, identity, "denied", zap.String("reason", "no verified client certificate"))
+		http.Error(w, "client certificate required", http.StatusUnauthorized)
+		return
+	}
+	if _, ok := h.allowed[identity]; len(h.allowed) > 0 && !ok {
+		h.auditLog(r, identity, "denied", zap.String("reason", "identity not allowed"))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *adminHandler) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	h.auditLog(r, identityFromTLS(r.TLS), "ok")
+	h.writeLogLevel(w)
+}
+
+func (h *adminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	identity := identityFromTLS(r.TLS)
+
+	var body logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.badRequest(w, r, identity, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(body.Level)); err != nil {
+		h.badRequest(w, r, identity, err)
+		return
+	}
+	duration, err := time.ParseDuration(body.Duration)
+	if err != nil {
+		h.badRequest(w, r, identity, fmt.Errorf("invalid duration: %w", err))
+		return
+	}
+	if h.maxOverride > 0 && duration > h.maxOverride {
+		h.badRequest(w, r, identity, fmt.Errorf("duration must not exceed %s", h.maxOverride))
+		return
+	}
+
+	override, err := h.levels.SetOverride(level, body.Identity, duration)
+	if err != nil {
+		h.badRequest(w, r, identity, err)
+		return
+	}
+
+	h.auditLog(r, identity, "ok",
+		zap.Stringer("level", override.Level),
+		zap.String("scope", body.Identity),
+		zap.Duration("duration", duration),
+		zap.Time("expires_at", override.ExpiresAt))
+	h.writeLogLevel(w)
+}
+
+func (h *adminHandler) clearLogLevel(w http.ResponseWriter, r *http.Request) {
+	cleared := h.levels.ClearOverride()
+	h.auditLog(r, identityFromTLS(r.TLS), "ok", zap.Bool("cleared", cleared))
+	h.writeLogLevel(w)
+}
+
+// goroutines writes the stack traces of all goroutines in the same format as an unrecovered panic.
+func (h *adminHandler) goroutines(w http.ResponseWriter, r *http.Request) {
+	h.auditLog(r, identityFromTLS(r.TLS), "ok")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := pprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		h.audit.Error("Failed to write goroutine dump", zap.Error(err))
+	}
+}
+
+// heap writes a heap profile in the pprof format. Setting the gc query parameter runs a garbage
+// collection first, so the profile reflects live objects only.
+func (h *adminHandler) heap(w http.ResponseWriter, r *http.Request) {
+	gc := r.URL.Query().Get("gc") != ""
+	h.auditLog(r, identityFromTLS(r.TLS), "ok", zap.Bool("gc", gc))
+	if gc {
+		runtime.GC()
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="heap.pprof"`)
+	if err := pprof.Lookup("heap").WriteTo(w, 0); err != nil {
+		h.audit.Error("Failed to write heap profile", zap.Error(err))
+	}
+}
+
+func (h *adminHandler) writeLogLevel(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logLevelResponse{
+		Base:     h.levels.Base(),
+		Override: h.levels.Override(),
+	})
+}
+
+func (h *adminHandler) badRequest(w http.ResponseWriter, r *http.Request, identity string, err error) {
+	h.auditLog(r, identity, "rejected", zap.Error(err))
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (h *adminHandler) auditLog(r *http.Request, identity, outcome string, fields ...zap.Field) {
+	h.audit.Info("Admin action",
+		append([]zap.Field{
+			zap.String("action", r.Method+" "+r.URL.Path),
+			zap.String("identity", identity),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("outcome", outcome),
+		}, fields...)...)
+}
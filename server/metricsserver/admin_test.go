@@ -0,0 +1,99 @@
+package metricsserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAdminRequest(method, target, body, identity string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if identity != "" {
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: identity}}}},
+		}
+	}
+	return req
+}
+
+func TestAdminHandler_Authentication(t *testing.T) {
+	levels, logs := newObservedLevelController(zapcore.InfoLevel)
+	h := newAdminHandler(AdminConfig{AllowedIdentities: []string{"admin"}}, levels)
+
+	tests := []struct {
+		name     string
+		identity string
+		wantCode int
+	}{
+		{name: "No Certificate", identity: "", wantCode: http.StatusUnauthorized},
+		{name: "Identity Not Allowed", identity: "client", wantCode: http.StatusForbidden},
+		{name: "Allowed Identity", identity: "admin", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/loglevel", "", tt.identity))
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+
+	assert.Equal(t, 3, logs.FilterMessage("Admin action").Len())
+}
+
+func TestAdminHandler_SetLogLevel(t *testing.T) {
+	levels, logs := newObservedLevelController(zapcore.InfoLevel)
+	h := newAdminHandler(AdminConfig{MaxOverride: time.Hour}, levels)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "Invalid Level", body: `{"level":"loud","duration":"1m"}`, wantCode: http.StatusBadRequest},
+		{name: "Invalid Duration", body: `{"level":"debug","duration":"soon"}`, wantCode: http.StatusBadRequest},
+		{name: "Duration Too Long", body: `{"level":"debug","duration":"2h"}`, wantCode: http.StatusBadRequest},
+		{name: "Scoped Override", body: `{"level":"debug","duration":"1m","identity":"client"}`, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newAdminRequest(http.MethodPut, "/admin/loglevel", tt.body, "admin"))
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+
+	if assert.NotNil(t, levels.Override()) {
+		assert.Equal(t, zapcore.DebugLevel, levels.Override().Level)
+		assert.Equal(t, "client", levels.Override().Identity)
+	}
+	assert.Equal(t, 3, logs.FilterField(zapcore.Field{Key: "outcome", Type: zapcore.StringType, String: "rejected"}).Len())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newAdminRequest(http.MethodDelete, "/admin/loglevel", "", "admin"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, levels.Override())
+}
+
+func TestAdminHandler_Diagnostics(t *testing.T) {
+	levels, _ := newObservedLevelController(zapcore.InfoLevel)
+	h := newAdminHandler(AdminConfig{}, levels)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/debug/goroutines", "", "admin"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/debug/heap?gc=1", "", "admin"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotZero(t, rec.Body.Len())
+}
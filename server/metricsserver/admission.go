@@ -0,0 +1,229 @@
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"sync"
+	"time"
+)
+
+// Reasons for which a request is not admitted, used as metric label values.
+const (
+	shedQueueFull    = "queue_full"
+	shedQueueTimeout = "queue_timeout"
+	shedCancelled    = "cancelled"
+)
+
+// backoffRatio is the factor the adaptive limit is multiplied by when a request is too slow.
+const backoffRatio = 0.9
+
+// AdmissionConfig configures the limit of concurrent Export calls.
+type AdmissionConfig struct {
+	Enabled      bool                `mapstructure:"enabled"`
+	MaxInFlight  int                 `mapstructure:"max_in_flight"`  // Limit of concurrent calls, the initial limit when adaptive
+	MaxQueued    int                 `mapstructure:"max_queued"`     // Calls that may wait for a slot
+	MaxQueueWait time.Duration       `mapstructure:"max_queue_wait"` // How long a call may wait for a slot
+	RetryAfter   time.Duration       `mapstructure:"retry_after"`    // Delay suggested to rejected clients
+	Adaptive     AdaptiveLimitConfig `mapstructure:"adaptive"`
+}
+
+// AdaptiveLimitConfig configures the adjustment of the concurrency limit to the observed latency.
+type AdaptiveLimitConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	MinLimit      int           `mapstructure:"min_limit"`
+	MaxLimit      int           `mapstructure:"max_limit"`
+	TargetLatency time.Duration `mapstructure:"target_latency"`
+}
+
+// limiter bounds the number of requests processed concurrently. Requests over the limit wait in a
+// bounded FIFO queue for a slot to free up, and are shed when the queue is full or they waited for
+// too long.
+//
+// With an adaptive limit, the limit follows the latency of the requests (AIMD): it is multiplied
+// by 0.9 when a request takes longer than the target latency, and grows by one for every
+// limit-worth of faster requests completed while at least half of the limit was in use. The slow
+// requests admitted before the last decrease do not decrease it again, so that a burst of slow
+// requests caused by one congestion event decreases the limit once.
+type limiter struct {
+	config  AdmissionConfig
+	metrics *serverMetrics
+
+	mutex       sync.Mutex
+	limit       float64
+	lastBackoff time.Time // When the adaptive limit was last decreased
+	inFlight    int
+	queue       []*waiter
+}
+
+// waiter is a request waiting in the queue. Its ready channel is closed once it is given a slot.
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newLimiter(config AdmissionConfig, metrics *serverMetrics) (*limiter, error) {
+	if config.MaxInFlight <= 0 {
+		return nil, fmt.Errorf("max_in_flight must be positive, got %d", config.MaxInFlight)
+	}
+	if config.MaxQueued < 0 {
+		return nil, fmt.Errorf("max_queued must not be negative, got %d", config.MaxQueued)
+	}
+	if config.MaxQueued > 0 && config.MaxQueueWait <= 0 {
+		return nil, fmt.Errorf("max_queue_wait must be positive with a queue, got %v", config.MaxQueueWait)
+	}
+	if adaptive := config.Adaptive; adaptive.Enabled {
+		if adaptive.MinLimit <= 0 || adaptive.MinLimit > config.MaxInFlight || config.MaxInFlight > adaptive.MaxLimit {
+			return nil, fmt.Errorf("the limits must satisfy 0 < min_limit <= max_in_flight <= max_limit, got %d, %d and %d",
+				adaptive.MinLimit, config.MaxInFlight, adaptive.MaxLimit)
+		}
+		if adaptive.TargetLatency <= 0 {
+			return nil, fmt.Errorf("target_latency must be positive, got %s", adaptive.TargetLatency)
+		}
+	}
+
+	l := &limiter{
+		config:  config,
+		metrics: metrics,
+		limit:   float64(config.MaxInFlight),
+	}
+	metrics.admissionLimit.Set(l.limit)
+	return l, nil
+}
+
+// acquire waits for a slot to process a request. It returns the reason the request is shed if no
+// slot is available, in which case release must not be called.
+func (l *limiter) acquire(ctx context.Context) (string, error) {
+	l.mutex.Lock()
+	if len(l.queue) == 0 && l.inFlight < int(l.limit) {
+		l.inFlight++
+		l.updateGauges()
+		l.mutex.Unlock()
+		return "", nil
+	}
+	if len(l.queue) >= l.config.MaxQueued {
+		l.mutex.Unlock()
+		return shedQueueFull, errors.New("too many requests in flight")
+	}
+	w := &waiter{ready: make(chan struct{})}
+	l.queue = append(l.queue, w)
+	l.updateGauges()
+	l.mutex.Unlock()
+
+	start := time.Now()
+	timeout := time.NewTimer(l.config.MaxQueueWait)
+	defer timeout.Stop()
+
+	var reason string
+	var err error
+	select {
+	case <-w.ready:
+	case <-timeout.C:
+		reason, err = shedQueueTimeout, fmt.Errorf("no capacity available within %s", l.config.MaxQueueWait)
+	case <-ctx.Done():
+		reason, err = shedCancelled, ctx.Err()
+	}
+	l.metrics.admissionQueueWait.Observe(time.Since(start).Seconds())
+	if err == nil {
+		return "", nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if w.granted {
+		// The slot was handed over while giving up, keep it.
+		return "", nil
+	}
+	for i, queued := range l.queue {
+		if queued == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	l.updateGauges()
+	return reason, err
+}
+
+// release frees the slot of a request that was processed in the given time, and hands it to the
+// next waiting request.
+func (l *limiter) release(latency time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.config.Adaptive.Enabled {
+		l.adapt(latency)
+	}
+	l.inFlight--
+	for len(l.queue) > 0 && l.inFlight < int(l.limit) {
+		w := l.queue[0]
+		l.queue = l.queue[1:]
+		w.granted = true
+		close(w.ready)
+		l.inFlight++
+	}
+	l.updateGauges()
+}
+
+// adapt adjusts the limit to the latency of a request. It must be called with the lock held, before
+// the request is removed from the in-flight requests.
+func (l *limiter) adapt(latency time.Duration) {
+	adaptive := l.config.Adaptive
+	now := time.Now()
+	switch {
+	case latency > adaptive.TargetLatency:
+		if now.Add(-latency).After(l.lastBackoff) {
+			l.limit = max(float64(adaptive.MinLimit), l.limit*backoffRatio)
+			l.lastBackoff = now
+		}
+	case l.inFlight*2 >= int(l.limit):
+		l.limit = min(float64(adaptive.MaxLimit), l.limit+1/l.limit)
+	}
+	l.metrics.admissionLimit.Set(float64(int(l.limit)))
+}
+
+// updateGauges must be called with the lock held.
+func (l *limiter) updateGauges() {
+	l.metrics.admissionInFlight.Set(float64(l.inFlight))
+	l.metrics.admissionQueued.Set(float64(len(l.queue)))
+}
+
+// UnaryInterceptorAdmission returns a gRPC unary interceptor that limits the number of Export
+// calls processed concurrently. Calls that cannot be admitted fail with RESOURCE_EXHAUSTED and a
+// RetryInfo detail telling the client when to retry. Other methods are not limited.
+func UnaryInterceptorAdmission(l *limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := req.(*pb.ExportMetricsServiceRequest); !ok {
+			return handler(ctx, req)
+		}
+
+		if reason, err := l.acquire(ctx); err != nil {
+			l.metrics.admissionShed.WithLabelValues(reason).Inc()
+			if reason == shedCancelled {
+				return nil, status.FromContextError(err).Err()
+			}
+			return nil, retryAfterError(codes.ResourceExhausted, err.Error(), l.config.RetryAfter)
+		}
+
+		start := time.Now()
+		defer func() {
+			l.release(time.Since(start))
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// retryAfterError returns an error with a RetryInfo detail that asks the client to retry after the
+// delay.
+func retryAfterError(code codes.Code, message string, retryAfter time.Duration) error {
+	st := status.New(code, message)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
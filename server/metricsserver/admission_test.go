@@ -0,0 +1,162 @@
+package metricsserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(t *testing.T, config AdmissionConfig) *limiter {
+	l, err := newLimiter(config, newServerMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func queueLength(l *limiter) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.queue)
+}
+
+func TestLimiter_QueueAndRelease(t *testing.T) {
+	l := newTestLimiter(t, AdmissionConfig{MaxInFlight: 1, MaxQueued: 1, MaxQueueWait: time.Minute})
+
+	_, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	admitted := make(chan error)
+	go func() {
+		_, err := l.acquire(context.Background())
+		admitted <- err
+	}()
+	assert.Eventually(t, func() bool { return queueLength(l) == 1 }, time.Second, time.Millisecond)
+
+	reason, err := l.acquire(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, shedQueueFull, reason)
+
+	l.release(time.Millisecond)
+	assert.NoError(t, <-admitted)
+	assert.Equal(t, 1, l.inFlight)
+}
+
+func TestLimiter_QueueTimeout(t *testing.T) {
+	l := newTestLimiter(t, AdmissionConfig{MaxInFlight: 1, MaxQueued: 1, MaxQueueWait: 10 * time.Millisecond})
+
+	_, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	reason, err := l.acquire(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, shedQueueTimeout, reason)
+	assert.Equal(t, 0, queueLength(l))
+}
+
+func TestLimiter_Adaptive(t *testing.T) {
+	config := AdmissionConfig{
+		MaxInFlight: 10,
+		Adaptive:    AdaptiveLimitConfig{Enabled: true, MinLimit: 9, MaxLimit: 11, TargetLatency: 100 * time.Millisecond},
+	}
+	tests := []struct {
+		name      string
+		inFlight  int
+		latency   time.Duration
+		wantLimit float64
+	}{
+		{"slow request backs off", 1, time.Second, 9},
+		{"fast request while busy grows", 5, time.Millisecond, 10.1},
+		{"fast request while idle keeps the limit", 1, time.Millisecond, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(t, config)
+			l.inFlight = tt.inFlight
+			l.release(tt.latency)
+			assert.InDelta(t, tt.wantLimit, l.limit, 1e-9)
+		})
+	}
+
+	l := newTestLimiter(t, config)
+	for i := 0; i < 5; i++ {
+		l.inFlight = 1
+		l.release(time.Second)
+	}
+	assert.Equal(t, 9.0, l.limit)
+}
+
+func TestLimiter_Adaptive_BackoffOncePerCongestion(t *testing.T) {
+	l := newTestLimiter(t, AdmissionConfig{
+		MaxInFlight: 10,
+		Adaptive:    AdaptiveLimitConfig{Enabled: true, MinLimit: 1, MaxLimit: 10, TargetLatency: 100 * time.Millisecond},
+	})
+
+	// A burst of slow requests admitted before the decrease decreases the limit once.
+	for i := 0; i < 5; i++ {
+		l.inFlight = 5
+		l.release(time.Second)
+	}
+	assert.InDelta(t, 9.0, l.limit, 1e-9)
+
+	// A slow request admitted after the decrease decreases it again.
+	l.lastBackoff = time.Now().Add(-2 * time.Second)
+	l.inFlight = 5
+	l.release(time.Second)
+	assert.InDelta(t, 8.1, l.limit, 1e-9)
+}
+
+func TestUnaryInterceptorAdmission(t *testing.T) {
+	l := newTestLimiter(t, AdmissionConfig{MaxInFlight: 1, RetryAfter: 2 * time.Second})
+	interceptor := UnaryInterceptorAdmission(l)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Unary"}
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	_, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	_, err = interceptor(context.Background(), &pb.ExportMetricsServiceRequest{}, info, handler)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+		if assert.True(t, ok) {
+			assert.Equal(t, 2*time.Second, retryInfo.GetRetryDelay().AsDuration())
+		}
+	}
+
+	// Only Export calls are limited.
+	resp, err := interceptor(context.Background(), &emptypb.Empty{}, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func TestNewLimiter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AdmissionConfig
+	}{
+		{"no concurrency", AdmissionConfig{MaxInFlight: 0}},
+		{"negative queue", AdmissionConfig{MaxInFlight: 1, MaxQueued: -1}},
+		{"queue without wait", AdmissionConfig{MaxInFlight: 1, MaxQueued: 1}},
+		{"min limit above initial limit", AdmissionConfig{MaxInFlight: 1,
+			Adaptive: AdaptiveLimitConfig{Enabled: true, MinLimit: 2, MaxLimit: 4, TargetLatency: time.Second}}},
+		{"no target latency", AdmissionConfig{MaxInFlight: 1,
+			Adaptive: AdaptiveLimitConfig{Enabled: true, MinLimit: 1, MaxLimit: 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLimiter(tt.config, newServerMetrics())
+			assert.Error(t, err)
+		})
+	}
+}
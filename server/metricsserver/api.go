@@ -0,0 +1,164 @@
+package metricsserver
+
+import (
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"metrics/server/pb/pv"
+	"metrics/server/version"
+	"time"
+)
+
+// service implements the gRPC services of the server.
+type service struct {
+	pb.UnimplementedMetricsServiceServer
+	pv.UnimplementedVersionServiceServer
+	pv.UnimplementedStatusServiceServer
+	logger         *zap.Logger
+	levels         *LevelController
+	storage        Storage
+	metrics        *serverMetrics
+	identityLabels *clientLabeler
+	capabilities   *capabilities
+	status         *statusTracker
+}
+
+// Export is a gRPC method of the MetricsService service that handles the exporting of metrics data.
+//
+// This method receives an ExportMetricsServiceRequest containing metrics data to be exported.
+// It processes the received metrics data, checks for any errors, and generates an appropriate response.
+// If there are errors in the received data, it returns a response with details about the errors.
+// Otherwise, it returns a response indicating successful processing.
+//
+// Parameters:
+// - ctx: The context.Context for the RPC call.
+// - req: The ExportMetricsServiceRequest containing the metrics data to be exported.
+//
+// Returns:
+// - *pb.ExportMetricsServiceResponse: The response containing the result of the export operation.
+// - error: An error, if any occurred during processing.
+func (s *service) Export(ctx context.Context,
+	req *pb.ExportMetricsServiceRequest) (*pb.ExportMetricsServiceResponse, error) {
+	s.loggerFor(ctx).Debug("Export method called", zap.Any("request", req))
+
+	hasErrors := false
+	var errorMessage string
+	rejectedDataPoints := 0
+
+	_, validateSpan := startStage(ctx, "validate")
+	stats := newRequestStats(proto.Size(req))
+	for _, resourceMetrics := range req.ResourceMetrics {
+		stats.resources++
+		for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+			stats.scopes++
+			for _, metric := range scopeMetrics.Metrics {
+				stats.addMetric(metric)
+				if reason := validateMetric(metric); reason != "" { // Example error condition
+					hasErrors = true
+					_, points := metricDataPoints(metric)
+					rejectedDataPoints += points
+					stats.rejected[reason] += points
+					errorMessage = fmt.Sprintf("metric rejected: %s", reason)
+				}
+			}
+		}
+	}
+	validateSpan.SetAttributes(
+		attribute.Int("otlp.data_points", stats.totalDataPoints()),
+		attribute.Int("otlp.rejected_data_points", rejectedDataPoints),
+	)
+	validateSpan.End()
+
+	s.metrics.recordIngestion(s.identityLabels.label(ctx), stats)
+	s.status.recordDataPoints(stats)
+
+	storeCtx, storeSpan := startStage(ctx, "store")
+	defer storeSpan.End()
+
+	response := &pb.ExportMetricsServiceResponse{}
+	if hasErrors {
+		// Build response with errors
+		response = &pb.ExportMetricsServiceResponse{
+			PartialSuccess: &pb.ExportMetricsPartialSuccess{
+				RejectedDataPoints: int64(rejectedDataPoints),
+				ErrorMessage:       errorMessage,
+			},
+		}
+	}
+
+	err := s.storage.Store(storeCtx, CachedRequest{
+		Request:   req,
+		RequestID: requestIDFromContext(ctx),
+		Timestamp: time.Now(),
+	}, hasErrors)
+	if err != nil {
+		s.loggerFor(ctx).Error("Failed to store request", zap.Error(err))
+		storeSpan.RecordError(err)
+		return nil, status.Errorf(codes.Unavailable, "failed to store request: %v", err)
+	}
+
+	return response, nil
+}
+
+// loggerFor returns the logger for a request, which honours a log level override scoped to the
+// identity of the caller and records the request and trace IDs of the request.
+func (s *service) loggerFor(ctx context.Context) *zap.Logger {
+	logger := s.logger
+	if s.levels != nil {
+		logger = s.levels.Logger(clientIdentity(ctx))
+	}
+	if requestID := requestIDFromContext(ctx); requestID != "" {
+		logger = logger.With(zap.String("request_id", requestID))
+	}
+	if fields := traceFields(ctx); fields != nil {
+		logger = logger.With(fields...)
+	}
+	return logger
+}
+
+// GetVersion retrieves the current version information. This method takes no parameters and returns a VersionResponse
+// message containing version information such as the build timestamp and Git commit SHA, the Go version and modules the
+// server was built with, and its uptime.
+func (s *service) GetVersion(context.Context, *emptypb.Empty) (*pv.VersionResponse, error) {
+	info := version.Get()
+
+	s.logger.Debug("GetVersion method called")
+	modules := make([]*pv.Module, 0, len(info.Modules))
+	for _, m := range info.Modules {
+		modules = append(modules, &pv.Module{Path: m.Path, Version: m.Version, Sum: m.Sum})
+	}
+	return &pv.VersionResponse{
+		BuildTimestamp: info.BuildTimestamp,
+		GitCommitSha:   info.CommitHash,
+		Version:        info.Version,
+		GoVersion:      info.GoVersion,
+		Modules:        modules,
+		VcsModified:    info.Modified,
+		StartTime:      timestamppb.New(info.StartTime),
+		Uptime:         durationpb.New(info.Uptime()),
+	}, nil
+}
+
+// GetCapabilities retrieves the capabilities of the server. This method takes no parameters and returns a
+// CapabilitiesResponse message describing the supported encodings, compression and receivers, the limits applied on
+// the listener of the caller and how metrics are validated.
+func (s *service) GetCapabilities(ctx context.Context, _ *emptypb.Empty) (*pv.CapabilitiesResponse, error) {
+	s.logger.Debug("GetCapabilities method called")
+	return s.capabilities.response(ListenerFromContext(ctx)), nil
+}
+
+// GetStatus retrieves the current status of the server. This method takes no parameters and returns a StatusResponse
+// message containing the uptime, the ingestion totals and rates, the cache occupancy, the open connections by client
+// identity and the top rejection reasons.
+func (s *service) GetStatus(context.Context, *emptypb.Empty) (*pv.StatusResponse, error) {
+	s.logger.Debug("GetStatus method called")
+	return s.status.status(time.Now()), nil
+}
@@ -0,0 +1,165 @@
+package metricsserver
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	labels, _ := newClientLabeler(DefaultClientLabelConfig)
+	s := &service{
+		logger:         logger,
+		storage:        NewRequestCache(10),
+		metrics:        newServerMetrics(),
+		identityLabels: labels,
+		status:         newStatusTracker(nil),
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name             string
+		request          *pb.ExportMetricsServiceRequest
+		wantErrors       bool
+		wantRejected     int64
+		wantErrorMessage string
+	}{
+		{
+			name: "Valid Request",
+			request: &pb.ExportMetricsServiceRequest{
+				ResourceMetrics: []*v1.ResourceMetrics{
+					{
+						ScopeMetrics: []*v1.ScopeMetrics{
+							{
+								Metrics: []*v1.Metric{
+									{Name: "metric1", Description: "desc1", Unit: "unit1", Data: &v1.Metric_Sum{}},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErrors: false,
+		},
+		{
+			name: "Empty Metric",
+			request: &pb.ExportMetricsServiceRequest{
+				ResourceMetrics: []*v1.ResourceMetrics{
+					{
+						ScopeMetrics: []*v1.ScopeMetrics{
+							{
+								Metrics: []*v1.Metric{
+									{Name: "", Description: "", Unit: "", Data: nil},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErrors:       true,
+			wantRejected:     0,
+			wantErrorMessage: "metric rejected: missing_name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Export(ctx, tt.request)
+			assert.NoError(t, err)
+
+			if tt.wantErrors {
+				assert.NotNil(t, resp.PartialSuccess)
+				assert.Equal(t, tt.wantRejected, resp.PartialSuccess.RejectedDataPoints)
+				assert.Equal(t, tt.wantErrorMessage, resp.PartialSuccess.ErrorMessage)
+			} else {
+				assert.Nil(t, resp.PartialSuccess)
+			}
+		})
+	}
+}
+
+func TestExport_RejectedDataPoints(t *testing.T) {
+	labels, _ := newClientLabeler(DefaultClientLabelConfig)
+	s := &service{
+		logger:         zap.NewNop(),
+		storage:        NewRequestCache(10),
+		metrics:        newServerMetrics(),
+		identityLabels: labels,
+		status:         newStatusTracker(nil),
+	}
+	req := &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{{
+			ScopeMetrics: []*v1.ScopeMetrics{{
+				Metrics: []*v1.Metric{
+					{Name: "metric1", Unit: "unit1", Data: &v1.Metric_Sum{Sum: &v1.Sum{
+						DataPoints: []*v1.NumberDataPoint{{}, {}, {}},
+					}}},
+					{Name: "metric2", Description: "desc2", Unit: "unit2", Data: &v1.Metric_Gauge{Gauge: &v1.Gauge{
+						DataPoints: []*v1.NumberDataPoint{{}, {}},
+					}}},
+				},
+			}},
+		}},
+	}
+
+	resp, err := s.Export(context.Background(), req)
+	assert.NoError(t, err)
+
+	// Every data point of the rejected metric is counted, in the response as in the metrics.
+	assert.Equal(t, int64(3), resp.GetPartialSuccess().GetRejectedDataPoints())
+	assert.Equal(t, "metric rejected: missing_description", resp.GetPartialSuccess().GetErrorMessage())
+	assert.Equal(t, 3.0, testutil.ToFloat64(s.metrics.rejectedDataPoints.WithLabelValues(rejectMissingDescription)))
+}
+
+func TestGetVersion(t *testing.T) {
+	s := &service{logger: zap.NewNop()}
+
+	resp, err := s.GetVersion(context.Background(), &emptypb.Empty{})
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, resp.GetVersion())
+		assert.NotEmpty(t, resp.GetGoVersion())
+		assert.NotNil(t, resp.GetStartTime())
+		assert.GreaterOrEqual(t, resp.GetUptime().AsDuration(), time.Duration(0))
+	}
+}
+
+func TestGetCapabilities(t *testing.T) {
+	s := &service{
+		logger: zap.NewNop(),
+		capabilities: newCapabilities(
+			[]ListenerConfig{{Name: "public"}, {Name: "sidecar", MaxRecvMsgSize: 1024}},
+			AdmissionConfig{Enabled: true, MaxInFlight: 8, MaxQueued: 16},
+			true,
+		),
+	}
+
+	tests := []struct {
+		listener        string
+		wantMaxRecvSize int64
+	}{
+		{"public", defaultMaxRecvMsgSize},
+		{"sidecar", 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.listener, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), listenerKey{}, tt.listener)
+			resp, err := s.GetCapabilities(ctx, &emptypb.Empty{})
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantMaxRecvSize, resp.GetLimits().GetMaxRecvMsgSizeBytes())
+				assert.Equal(t, int32(8), resp.GetLimits().GetMaxInFlightExports())
+				assert.Equal(t, int32(16), resp.GetLimits().GetMaxQueuedExports())
+				assert.True(t, resp.GetLimits().GetMemoryLimit())
+				assert.Contains(t, resp.GetCompressions(), "gzip")
+				assert.True(t, resp.GetValidation().GetPartialSuccess())
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package metricsserver
+
+import (
+	"cmp"
+	"metrics/server/pb/pv"
+)
+
+// defaultMaxRecvMsgSize is the limit gRPC applies to received messages unless configured otherwise.
+const defaultMaxRecvMsgSize = 4 * 1024 * 1024
+
+// Capabilities advertised by GetCapabilities. The gzip compressor is registered by server.go.
+var (
+	supportedEncodings    = []string{"proto"}
+	supportedCompressions = []string{"gzip"}
+	supportedReceivers    = []string{"otlp_grpc"}
+)
+
+// capabilities describes the configuration of the server that clients may adapt to.
+type capabilities struct {
+	maxRecvMsgSizes map[string]int // By listener name
+	admission       AdmissionConfig
+	memoryLimit     bool
+}
+
+func newCapabilities(listeners []ListenerConfig, admission AdmissionConfig, memoryLimit bool) *capabilities {
+	c := &capabilities{
+		maxRecvMsgSizes: make(map[string]int, len(listeners)),
+		admission:       admission,
+		memoryLimit:     memoryLimit,
+	}
+	for _, l := range listeners {
+		c.maxRecvMsgSizes[l.Name] = cmp.Or(l.MaxRecvMsgSize, defaultMaxRecvMsgSize)
+	}
+	return c
+}
+
+// response returns the capabilities for a client connected to the listener.
+func (c *capabilities) response(listener string) *pv.CapabilitiesResponse {
+	limits := &pv.Limits{
+		MaxRecvMsgSizeBytes: int64(c.maxRecvMsgSizes[listener]),
+		MemoryLimit:         c.memoryLimit,
+	}
+	if c.admission.Enabled {
+		limits.MaxInFlightExports = int32(c.admission.MaxInFlight)
+		limits.MaxQueuedExports = int32(c.admission.MaxQueued)
+		limits.AdaptiveLimit = c.admission.Adaptive.Enabled
+	}
+
+	return &pv.CapabilitiesResponse{
+		Encodings:    supportedEncodings,
+		Compressions: supportedCompressions,
+		Receivers:    supportedReceivers,
+		Limits:       limits,
+		Validation: &pv.ValidationPolicy{
+			RequiredFields:   []string{"name", "description", "unit", "data"},
+			PartialSuccess:   true,
+			RejectionReasons: []string{rejectMissingName, rejectMissingDescription, rejectMissingUnit, rejectMissingData},
+		},
+	}
+}
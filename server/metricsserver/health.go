@@ -0,0 +1,254 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HealthConfig configures the readiness checks of the server.
+type HealthConfig struct {
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+// errPending is the state of a condition that has not been evaluated yet.
+var errPending = errors.New("not checked yet")
+
+// healthTracker derives the readiness of the server from a set of named conditions, such as the
+// certificates being valid or the log storage being writable.
+//
+// A condition can be limited to some gRPC services, otherwise it applies to all of them. The
+// serving status of every service in the standard gRPC health service, and of the server as a
+// whole, is kept in sync with the conditions. Transitions are logged and exported as gauges.
+type healthTracker struct {
+	logger     *zap.Logger
+	grpcHealth *health.Server
+	metrics    *serverMetrics
+	services   []string
+	conditions map[string]*condition
+	mutex      sync.Mutex
+}
+
+type condition struct {
+	services   []string // Services that depend on the condition, all of them if empty
+	err        error    // Why the condition is not met, nil if it is
+	reportOnly bool     // Logged and exported, without affecting the readiness
+}
+
+// newHealthTracker creates a tracker that reports the status of the given gRPC services.
+func newHealthTracker(logger *zap.Logger, grpcHealth *health.Server, metrics *serverMetrics, services ...string) *healthTracker {
+	h := &healthTracker{
+		logger:     logger,
+		grpcHealth: grpcHealth,
+		metrics:    metrics,
+		services:   services,
+		conditions: make(map[string]*condition),
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.updateServingStatus()
+	return h
+}
+
+// AddCondition registers a condition, which is not met until it is first set. The services limit
+// the condition to the readiness of those gRPC services.
+func (h *healthTracker) AddCondition(name string, services ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.conditions[name] = &condition{services: services, err: errPending}
+	h.metrics.healthCondition.WithLabelValues(name).Set(0)
+	h.updateServingStatus()
+}
+
+// SetCondition records the state of a condition: met when err is nil, not met otherwise.
+func (h *healthTracker) SetCondition(name string, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	c, ok := h.conditions[name]
+	if !ok {
+		c = &condition{err: errPending}
+		h.conditions[name] = c
+	}
+	previous := c.err
+	c.err = err
+
+	switch {
+	case err == nil && previous != nil:
+		h.logger.Info("Health condition met", zap.String("condition", name))
+		h.metrics.healthCondition.WithLabelValues(name).Set(1)
+	case err != nil && (previous == nil || previous == errPending):
+		h.logger.Warn("Health condition not met", zap.String("condition", name), zap.Error(err))
+		h.metrics.healthCondition.WithLabelValues(name).Set(0)
+	}
+	h.updateServingStatus()
+}
+
+// AddCheck registers a condition whose state is determined by calling check immediately and then
+// every interval, until the context is done.
+func (h *healthTracker) AddCheck(ctx context.Context, name string, interval time.Duration, check func(context.Context) error, services ...string) {
+	h.AddCondition(name, services...)
+	h.runCheck(ctx, name, interval, check)
+}
+
+// AddReportOnlyCheck registers a check like AddCheck, whose transitions are logged and exported
+// but which does not affect the readiness of the server, for the dependencies that ingestion does
+// not need.
+func (h *healthTracker) AddReportOnlyCheck(ctx context.Context, name string, interval time.Duration, check func(context.Context) error) {
+	h.mutex.Lock()
+	h.conditions[name] = &condition{err: errPending, reportOnly: true}
+	h.metrics.healthCondition.WithLabelValues(name).Set(0)
+	h.mutex.Unlock()
+	h.runCheck(ctx, name, interval, check)
+}
+
+// runCheck sets a condition by calling check immediately and then every interval, until the
+// context is done.
+func (h *healthTracker) runCheck(ctx context.Context, name string, interval time.Duration, check func(context.Context) error) {
+	h.SetCondition(name, check(ctx))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.SetCondition(name, check(ctx))
+			}
+		}
+	}()
+}
+
+// Shutdown marks the server as not ready for good, as it is about to stop. The gRPC health service
+// reports every service as not serving from then on.
+func (h *healthTracker) Shutdown() {
+	h.SetCondition("shutting_down", errShuttingDown)
+	h.grpcHealth.Shutdown()
+}
+
+// Ready reports whether all conditions are met, along with the reasons of those that are not.
+func (h *healthTracker) Ready() (bool, map[string]string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	failing := make(map[string]string)
+	for name, c := range h.conditions {
+		if c.err != nil && !c.reportOnly {
+			failing[name] = c.err.Error()
+		}
+	}
+	return len(failing) == 0, failing
+}
+
+// updateServingStatus publishes the status of every service, and of the server as a whole under
+// the empty service name. It must be called with the mutex held.
+func (h *healthTracker) updateServingStatus() {
+	ready := true
+	for _, c := range h.conditions {
+		ready = ready && (c.err == nil || c.reportOnly)
+	}
+	h.setServingStatus("", ready)
+
+	for _, service := range h.services {
+		serving := true
+		for _, c := range h.conditions {
+			if c.err != nil && !c.reportOnly && (len(c.services) == 0 || slices.Contains(c.services, service)) {
+				serving = false
+			}
+		}
+		h.setServingStatus(service, serving)
+	}
+}
+
+func (h *healthTracker) setServingStatus(service string, serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	h.grpcHealth.SetServingStatus(service, status)
+	if serving {
+		h.metrics.serviceReady.WithLabelValues(service).Set(1)
+	} else {
+		h.metrics.serviceReady.WithLabelValues(service).Set(0)
+	}
+}
+
+// livenessHandler serves /healthz. The process is considered alive as long as it answers.
+func (h *healthTracker) livenessHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// readinessHandler serves /readyz, which fails with 503 and lists the conditions that are not met
+// while the server is not ready.
+func (h *healthTracker) readinessHandler(w http.ResponseWriter, _ *http.Request) {
+	ready, failing := h.Ready()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Ready   bool              `json:"ready"`
+		Failing map[string]string `json:"failing,omitempty"`
+	}{ready, failing})
+}
+
+// certificateCheck fails once a certificate in the chain has expired or is not valid yet.
+func certificateCheck(cert tls.Certificate) func(context.Context) error {
+	return func(context.Context) error {
+		now := time.Now()
+		for _, der := range cert.Certificate {
+			c, err := x509.ParseCertificate(der)
+			if err != nil {
+				return err
+			}
+			if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+				return fmt.Errorf("certificate %q is only valid from %s to %s",
+					c.Subject.CommonName, c.NotBefore.Format(time.RFC3339), c.NotAfter.Format(time.RFC3339))
+			}
+		}
+		return nil
+	}
+}
+
+// WritableCheck is a readiness check that fails when no file can be created in the directory.
+func WritableCheck(dir string) func(context.Context) error {
+	return func(context.Context) error {
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		return os.Remove(f.Name())
+	}
+}
+
+// reachableCheck fails when no TCP connection can be established to the address.
+func reachableCheck(address string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
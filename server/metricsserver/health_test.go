@@ -0,0 +1,135 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func servingStatus(t *testing.T, s *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := s.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	assert.NoError(t, err)
+	return resp.GetStatus()
+}
+
+func TestHealthTracker_ServiceStatus(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	grpcHealth := health.NewServer()
+	h := newHealthTracker(zap.New(core), grpcHealth, newServerMetrics(), "metrics", "version")
+
+	h.AddCondition("certificates")
+	h.AddCondition("storage", "metrics")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, grpcHealth, ""))
+
+	h.SetCondition("certificates", nil)
+	h.SetCondition("storage", errors.New("read-only file system"))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, grpcHealth, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, grpcHealth, "metrics"))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, grpcHealth, "version"))
+
+	ready, failing := h.Ready()
+	assert.False(t, ready)
+	assert.Equal(t, map[string]string{"storage": "read-only file system"}, failing)
+
+	h.SetCondition("storage", nil)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, grpcHealth, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, grpcHealth, "metrics"))
+
+	assert.Equal(t, 2, logs.FilterMessage("Health condition met").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Health condition not met").Len())
+}
+
+func TestHealthTracker_ReadinessHandler(t *testing.T) {
+	h := newHealthTracker(zap.NewNop(), health.NewServer(), newServerMetrics())
+	h.AddCondition("storage")
+
+	rec := httptest.NewRecorder()
+	h.readinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"ready": false, "failing": {"storage": "not checked yet"}}`, rec.Body.String())
+
+	h.SetCondition("storage", nil)
+	rec = httptest.NewRecorder()
+	h.readinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ready": true}`, rec.Body.String())
+}
+
+func TestHealthTracker_AddCheck(t *testing.T) {
+	h := newHealthTracker(zap.NewNop(), health.NewServer(), newServerMetrics())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := make(chan struct{}, 10)
+	h.AddCheck(ctx, "check", 5*time.Millisecond, func(context.Context) error {
+		calls <- struct{}{}
+		return nil
+	})
+
+	ready, _ := h.Ready()
+	assert.True(t, ready)
+	<-calls
+	<-calls // Checked again after the interval
+}
+
+func TestHealthTracker_AddReportOnlyCheck(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	grpcHealth := health.NewServer()
+	h := newHealthTracker(zap.New(core), grpcHealth, newServerMetrics(), "metrics")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h.AddReportOnlyCheck(ctx, "trace_exporter", time.Hour, func(context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	// The failing check is logged, but the server stays ready.
+	ready, failing := h.Ready()
+	assert.True(t, ready)
+	assert.Empty(t, failing)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, grpcHealth, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, grpcHealth, "metrics"))
+	assert.Equal(t, 1, logs.FilterMessage("Health condition not met").Len())
+}
+
+func TestCertificateCheck(t *testing.T) {
+	newCertificate := func(notBefore, notAfter time.Time) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "test"},
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		assert.NoError(t, err)
+		return tls.Certificate{Certificate: [][]byte{der}}
+	}
+
+	now := time.Now()
+	assert.NoError(t, certificateCheck(newCertificate(now.Add(-time.Hour), now.Add(time.Hour)))(context.Background()))
+	assert.Error(t, certificateCheck(newCertificate(now.Add(-2*time.Hour), now.Add(-time.Hour)))(context.Background()))
+	assert.Error(t, certificateCheck(newCertificate(now.Add(time.Hour), now.Add(2*time.Hour)))(context.Background()))
+}
+
+func TestWritableCheck(t *testing.T) {
+	assert.NoError(t, WritableCheck(t.TempDir())(context.Background()))
+	assert.Error(t, WritableCheck("/nonexistent")(context.Background()))
+}
@@ -0,0 +1,127 @@
+package metricsserver
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/peer"
+	"hash/fnv"
+	"net"
+	"sync"
+)
+
+// Strategies for deriving the client label of request metrics.
+const (
+	clientLabelIdentity = "identity" // Common name of the client certificate
+	clientLabelIP       = "ip"       // Client IP address, without the ephemeral port
+	clientLabelHash     = "hash"     // Identity hashed into a fixed number of buckets
+	clientLabelNone     = "none"     // No client label value at all
+)
+
+// overflowLabel replaces label values once the limit of distinct values has been reached.
+const overflowLabel = "other"
+
+// ClientLabelConfig configures how the client label of the request metrics is derived.
+type ClientLabelConfig struct {
+	Strategy    string `mapstructure:"strategy"`
+	MaxValues   int    `mapstructure:"max_values"`
+	HashBuckets int    `mapstructure:"hash_buckets"`
+}
+
+// DefaultClientLabelConfig labels requests by client identity, with at most 100 distinct values.
+var DefaultClientLabelConfig = ClientLabelConfig{
+	Strategy:    clientLabelIdentity,
+	MaxValues:   100,
+	HashBuckets: 16,
+}
+
+// clientLabeler derives a label value identifying the client of a request, while bounding the
+// number of distinct values it ever returns. The first MaxValues distinct values are returned as
+// they are, any value seen after that is reported as "other".
+type clientLabeler struct {
+	strategy    string
+	maxValues   int
+	hashBuckets uint32
+	seen        map[string]struct{}
+	mutex       sync.RWMutex
+}
+
+func newClientLabeler(config ClientLabelConfig) (*clientLabeler, error) {
+	switch config.Strategy {
+	case clientLabelIdentity, clientLabelIP, clientLabelNone:
+	case clientLabelHash:
+		if config.HashBuckets <= 0 {
+			return nil, fmt.Errorf("hash_buckets must be positive, got %d", config.HashBuckets)
+		}
+	default:
+		return nil, fmt.Errorf("unknown client label strategy %q", config.Strategy)
+	}
+	if config.MaxValues <= 0 {
+		return nil, fmt.Errorf("max_values must be positive, got %d", config.MaxValues)
+	}
+
+	return &clientLabeler{
+		strategy:    config.Strategy,
+		maxValues:   config.MaxValues,
+		hashBuckets: uint32(config.HashBuckets),
+		seen:        make(map[string]struct{}),
+	}, nil
+}
+
+// label returns the client label value for a request.
+func (l *clientLabeler) label(ctx context.Context) string {
+	var value string
+	switch l.strategy {
+	case clientLabelIdentity:
+		value = clientIdentity(ctx)
+	case clientLabelIP:
+		value = clientIP(ctx)
+	case clientLabelHash:
+		// Fall back to the IP address so callers without a certificate are still spread out.
+		key := clientIdentity(ctx)
+		if key == unknownIdentity {
+			key = clientIP(ctx)
+		}
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		value = fmt.Sprintf("bucket-%d", h.Sum32()%l.hashBuckets)
+	case clientLabelNone:
+		return ""
+	}
+
+	return l.bound(value)
+}
+
+// bound returns value if it was seen before or there is still room for it, "other" otherwise.
+func (l *clientLabeler) bound(value string) string {
+	l.mutex.RLock()
+	_, ok := l.seen[value]
+	l.mutex.RUnlock()
+	if ok {
+		return value
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, ok := l.seen[value]; ok {
+		return value
+	}
+	if len(l.seen) >= l.maxValues {
+		return overflowLabel
+	}
+	l.seen[value] = struct{}{}
+	return value
+}
+
+// clientIP returns the IP address of the caller without its port.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		// Not a host:port pair, e.g. a Unix domain socket.
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,76 @@
+package metricsserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/peer"
+	"net"
+	"testing"
+)
+
+func peerContext(ip string, port int) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: port},
+	})
+}
+
+func TestClientLabeler_IPStrategyDropsPort(t *testing.T) {
+	labels, err := newClientLabeler(ClientLabelConfig{Strategy: clientLabelIP, MaxValues: 10})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.1", labels.label(peerContext("10.0.0.1", 50001)))
+	assert.Equal(t, "10.0.0.1", labels.label(peerContext("10.0.0.1", 50002)))
+}
+
+func TestClientLabeler_Overflow(t *testing.T) {
+	labels, err := newClientLabeler(ClientLabelConfig{Strategy: clientLabelIP, MaxValues: 2})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.1", labels.label(peerContext("10.0.0.1", 1)))
+	assert.Equal(t, "10.0.0.2", labels.label(peerContext("10.0.0.2", 1)))
+	assert.Equal(t, overflowLabel, labels.label(peerContext("10.0.0.3", 1)))
+	// Values admitted before the limit was reached keep their own label.
+	assert.Equal(t, "10.0.0.1", labels.label(peerContext("10.0.0.1", 2)))
+}
+
+func TestClientLabeler_HashStrategy(t *testing.T) {
+	labels, err := newClientLabeler(ClientLabelConfig{Strategy: clientLabelHash, MaxValues: 10, HashBuckets: 4})
+	assert.NoError(t, err)
+
+	first := labels.label(peerContext("10.0.0.1", 1))
+	assert.Regexp(t, `^bucket-[0-3]$`, first)
+	assert.Equal(t, first, labels.label(peerContext("10.0.0.1", 2)))
+}
+
+func TestClientLabeler_NoneStrategy(t *testing.T) {
+	labels, err := newClientLabeler(ClientLabelConfig{Strategy: clientLabelNone, MaxValues: 1})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", labels.label(peerContext("10.0.0.1", 1)))
+	assert.Equal(t, "", labels.label(peerContext("10.0.0.2", 1)))
+}
+
+func TestClientLabeler_IdentityWithoutCertificate(t *testing.T) {
+	labels, err := newClientLabeler(ClientLabelConfig{Strategy: clientLabelIdentity, MaxValues: 1})
+	assert.NoError(t, err)
+
+	assert.Equal(t, unknownIdentity, labels.label(peerContext("10.0.0.1", 1)))
+}
+
+func TestNewClientLabeler_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClientLabelConfig
+	}{
+		{name: "Unknown Strategy", config: ClientLabelConfig{Strategy: "address", MaxValues: 10}},
+		{name: "No Hash Buckets", config: ClientLabelConfig{Strategy: clientLabelHash, MaxValues: 10}},
+		{name: "No Max Values", config: ClientLabelConfig{Strategy: clientLabelIdentity}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newClientLabeler(tt.config)
+			assert.Error(t, err)
+		})
+	}
+}
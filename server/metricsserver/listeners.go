@@ -0,0 +1,223 @@
+package metricsserver
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLS modes of a listener.
+const (
+	TLSModeMutual    = "mtls"      // Clients must present a certificate signed by the client CAs
+	TLSModeServer    = "tls"       // Only the server presents a certificate
+	TLSModePlaintext = "plaintext" // No TLS, only allowed on loopback addresses and Unix sockets
+)
+
+// unixScheme prefixes the addresses of Unix domain sockets.
+const unixScheme = "unix://"
+
+// defaultListenerName is the name of the listener configured by WithAddress and WithListener.
+const defaultListenerName = "default"
+
+// ListenerConfig configures an address the gRPC server serves on.
+type ListenerConfig struct {
+	Name           string          `mapstructure:"name"`              // Recorded in the metrics and the access log, the address if empty
+	Address        string          `mapstructure:"address"`           // TCP address, or unix:// followed by the path of a Unix socket
+	TLS            string          `mapstructure:"tls"`               // mtls (default), tls or plaintext
+	MaxRecvMsgSize int             `mapstructure:"max_recv_msg_size"` // Maximum size of a received message in bytes, 4 MiB if 0
+	Keepalive      KeepaliveConfig `mapstructure:"keepalive"`
+
+	// Listener, when set, is served instead of listening on the address.
+	Listener net.Listener `mapstructure:"-"`
+}
+
+// KeepaliveConfig configures the keepalive enforcement and parameters of the connections of a
+// listener. Unset values take the defaults of the server.
+type KeepaliveConfig struct {
+	MinTime               time.Duration `mapstructure:"min_time"`              // Clients pinging more often are disconnected
+	PermitWithoutStream   *bool         `mapstructure:"permit_without_stream"` // Allow pings without active streams
+	MaxConnectionIdle     time.Duration `mapstructure:"max_connection_idle"`
+	MaxConnectionAge      time.Duration `mapstructure:"max_connection_age"`
+	MaxConnectionAgeGrace time.Duration `mapstructure:"max_connection_age_grace"`
+	Time                  time.Duration `mapstructure:"time"`    // Idle time before the server pings the client
+	Timeout               time.Duration `mapstructure:"timeout"` // Wait for the ping ack before closing the connection
+}
+
+// serverOptions returns the keepalive options of a gRPC server, falling back on kaep and kasp.
+func (k KeepaliveConfig) serverOptions() []grpc.ServerOption {
+	policy := keepalive.EnforcementPolicy{
+		MinTime:             cmp.Or(k.MinTime, kaep.MinTime),
+		PermitWithoutStream: kaep.PermitWithoutStream,
+	}
+	if k.PermitWithoutStream != nil {
+		policy.PermitWithoutStream = *k.PermitWithoutStream
+	}
+	params := keepalive.ServerParameters{
+		MaxConnectionIdle:     cmp.Or(k.MaxConnectionIdle, kasp.MaxConnectionIdle),
+		MaxConnectionAge:      cmp.Or(k.MaxConnectionAge, kasp.MaxConnectionAge),
+		MaxConnectionAgeGrace: cmp.Or(k.MaxConnectionAgeGrace, kasp.MaxConnectionAgeGrace),
+		Time:                  cmp.Or(k.Time, kasp.Time),
+		Timeout:               cmp.Or(k.Timeout, kasp.Timeout),
+	}
+	return []grpc.ServerOption{grpc.KeepaliveEnforcementPolicy(policy), grpc.KeepaliveParams(params)}
+}
+
+// grpcListener is a listener together with the gRPC server serving it. Every listener gets its own
+// gRPC server, as the credentials, keepalive and message size are options of the server.
+type grpcListener struct {
+	config   ListenerConfig
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// network returns the network and address to listen on.
+func (c ListenerConfig) network() (string, string) {
+	if path, ok := strings.CutPrefix(c.Address, unixScheme); ok {
+		return "unix", path
+	}
+	return "tcp", c.Address
+}
+
+// validateListeners fills in the defaults of the listener configurations, and checks that they can
+// be served with the TLS configuration.
+func validateListeners(listeners []ListenerConfig, tlsConfig *tls.Config) ([]ListenerConfig, error) {
+	if len(listeners) == 0 {
+		return nil, errors.New("at least one listener is required")
+	}
+
+	validated := make([]ListenerConfig, 0, len(listeners))
+	names := make(map[string]bool)
+	for _, c := range listeners {
+		if c.Address == "" && c.Listener == nil {
+			return nil, fmt.Errorf("listener %q has no address", c.Name)
+		}
+		c.Name = cmp.Or(c.Name, c.Address)
+		c.TLS = cmp.Or(c.TLS, TLSModeMutual)
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate listener name %q", c.Name)
+		}
+		names[c.Name] = true
+
+		switch c.TLS {
+		case TLSModeMutual, TLSModeServer:
+			if tlsConfig == nil {
+				return nil, fmt.Errorf("listener %q requires a TLS configuration for TLS mode %q", c.Name, c.TLS)
+			}
+		case TLSModePlaintext:
+			// An injected listener is trusted to be local.
+			if c.Listener == nil && !isLocalAddress(c.Address) {
+				return nil, fmt.Errorf("listener %q can only be plaintext on a loopback address or a Unix socket, got %q", c.Name, c.Address)
+			}
+		default:
+			return nil, fmt.Errorf("listener %q has unknown TLS mode %q", c.Name, c.TLS)
+		}
+		if c.MaxRecvMsgSize < 0 {
+			return nil, fmt.Errorf("listener %q has a negative max_recv_msg_size", c.Name)
+		}
+		validated = append(validated, c)
+	}
+	return validated, nil
+}
+
+// isLocalAddress reports whether the address is a Unix socket or a TCP address on a loopback
+// interface, which cannot be reached from other hosts.
+func isLocalAddress(address string) bool {
+	if strings.HasPrefix(address, unixScheme) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serverOptions returns the options of the gRPC server of the listener. Mutual TLS requires a
+// verified client certificate whatever the client authentication of the TLS configuration.
+func (c ListenerConfig) serverOptions(tlsConfig *tls.Config, metrics *serverMetrics) []grpc.ServerOption {
+	opts := append(c.Keepalive.serverOptions(), grpc.StatsHandler(&listenerStatsHandler{name: c.Name, metrics: metrics}))
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	switch c.TLS {
+	case TLSModeMutual:
+		config := tlsConfig.Clone()
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	case TLSModeServer:
+		config := tlsConfig.Clone()
+		config.ClientAuth = tls.NoClientCert
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	return opts
+}
+
+// listen listens on the address of the listener. A Unix socket left over by a previous process is
+// removed first, as it would prevent listening on its path.
+func (c ListenerConfig) listen(ctx context.Context, lc *net.ListenConfig) (net.Listener, error) {
+	network, address := c.network()
+	if network == "unix" {
+		if info, err := os.Stat(address); err == nil && info.Mode().Type() == fs.ModeSocket {
+			if err := os.Remove(address); err != nil {
+				return nil, fmt.Errorf("failed to remove stale socket %s: %w", address, err)
+			}
+		}
+	}
+	l, err := lc.Listen(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", c.Address, err)
+	}
+	return l, nil
+}
+
+// listenerKey is the context key of the name of the listener a connection was accepted on.
+type listenerKey struct{}
+
+// ListenerFromContext returns the name of the listener the RPC of the context was received on, or
+// an empty string outside of an RPC.
+func ListenerFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(listenerKey{}).(string)
+	return name
+}
+
+// listenerStatsHandler tags the connections of a listener with its name, which the contexts of
+// their RPCs inherit, and counts its open connections.
+type listenerStatsHandler struct {
+	name    string
+	metrics *serverMetrics
+}
+
+func (h *listenerStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, listenerKey{}, h.name)
+}
+
+func (h *listenerStatsHandler) HandleConn(_ context.Context, s stats.ConnStats) {
+	connections := h.metrics.listenerConnections.WithLabelValues(h.name)
+	switch s.(type) {
+	case *stats.ConnBegin:
+		connections.Inc()
+	case *stats.ConnEnd:
+		connections.Dec()
+	}
+}
+
+func (h *listenerStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *listenerStatsHandler) HandleRPC(context.Context, stats.RPCStats) {}
@@ -0,0 +1,137 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidateListeners(t *testing.T) {
+	tlsConfig := &tls.Config{}
+	tests := []struct {
+		name      string
+		listeners []ListenerConfig
+		tlsConfig *tls.Config
+		wantErr   bool
+	}{
+		{"mtls by default", []ListenerConfig{{Address: ":8080"}}, tlsConfig, false},
+		{"mtls without TLS configuration", []ListenerConfig{{Address: ":8080"}}, nil, true},
+		{"server-only TLS", []ListenerConfig{{Address: ":8080", TLS: TLSModeServer}}, tlsConfig, false},
+		{"plaintext on loopback", []ListenerConfig{{Address: "127.0.0.1:8080", TLS: TLSModePlaintext}}, nil, false},
+		{"plaintext on localhost", []ListenerConfig{{Address: "localhost:8080", TLS: TLSModePlaintext}}, nil, false},
+		{"plaintext on IPv6 loopback", []ListenerConfig{{Address: "[::1]:8080", TLS: TLSModePlaintext}}, nil, false},
+		{"plaintext on Unix socket", []ListenerConfig{{Address: "unix:///tmp/metrics.sock", TLS: TLSModePlaintext}}, nil, false},
+		{"plaintext on all interfaces", []ListenerConfig{{Address: ":8080", TLS: TLSModePlaintext}}, nil, true},
+		{"plaintext on external address", []ListenerConfig{{Address: "10.0.0.1:8080", TLS: TLSModePlaintext}}, nil, true},
+		{"unknown TLS mode", []ListenerConfig{{Address: ":8080", TLS: "ssl"}}, tlsConfig, true},
+		{"no address", []ListenerConfig{{Name: "grpc"}}, tlsConfig, true},
+		{"duplicate names", []ListenerConfig{{Name: "grpc", Address: ":8080"}, {Name: "grpc", Address: ":8081"}}, tlsConfig, true},
+		{"duplicate addresses", []ListenerConfig{{Address: ":8080"}, {Address: ":8080"}}, tlsConfig, true},
+		{"negative message size", []ListenerConfig{{Address: ":8080", MaxRecvMsgSize: -1}}, tlsConfig, true},
+		{"no listeners", nil, tlsConfig, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateListeners(tt.listeners, tt.tlsConfig)
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestValidateListeners_Defaults(t *testing.T) {
+	listeners, err := validateListeners([]ListenerConfig{{Address: ":8080"}}, &tls.Config{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, ":8080", listeners[0].Name)
+		assert.Equal(t, TLSModeMutual, listeners[0].TLS)
+	}
+}
+
+func TestServer_MultipleListeners(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	registry := prometheus.NewRegistry()
+
+	var mutex sync.Mutex
+	var seen []string
+	recordListener := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		mutex.Lock()
+		seen = append(seen, ListenerFromContext(ctx))
+		mutex.Unlock()
+		return handler(ctx, req)
+	}
+
+	srv, err := New(
+		WithListeners(
+			ListenerConfig{Name: "loopback", Address: "127.0.0.1:0", TLS: TLSModePlaintext},
+			ListenerConfig{Name: "sidecar", Address: "unix://" + socket, TLS: TLSModePlaintext, MaxRecvMsgSize: 64},
+		),
+		WithMetricsAddress("127.0.0.1:0"),
+		WithRegistry(registry),
+		WithUnaryInterceptor(recordListener),
+	)
+	if !assert.NoError(t, err) || !assert.NoError(t, srv.Start(context.Background())) {
+		return
+	}
+	defer srv.Stop(context.Background())
+	assert.Equal(t, srv.Addr(), srv.ListenerAddr("loopback"))
+	assert.Nil(t, srv.ListenerAddr("unknown"))
+
+	for _, target := range []string{srv.ListenerAddr("loopback").String(), "unix://" + socket} {
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, err = pb.NewMetricsServiceClient(conn).Export(context.Background(), &pb.ExportMetricsServiceRequest{})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"loopback", "sidecar"}, seen)
+
+	// The message size limit only applies to the listener it is configured on.
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, err = pb.NewMetricsServiceClient(conn).Export(context.Background(), &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{{SchemaUrl: strings.Repeat("m", 100)}},
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	assert.Equal(t, 1.0, requestCount(t, registry, "sidecar"))
+}
+
+// requestCount returns the number of successful requests received on the listener.
+func requestCount(t *testing.T, registry *prometheus.Registry, listener string) float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count float64
+	for _, family := range families {
+		if family.GetName() != "grpc_request_count" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["listener"] == listener && labels["code"] == codes.OK.String() {
+				count += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,196 @@
+package metricsserver
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LevelController owns the log level of the server and allows it to be changed at runtime.
+//
+// The configured level acts as the base level. An override can raise or lower it for a limited
+// time, either for the whole server or only for the requests of a single client identity. Once the
+// override expires the base level is restored automatically.
+//
+// The underlying zap core is built at the lowest level any logger may currently need (the floor),
+// and every logger handed out by the controller is wrapped in a core that applies its own level.
+// The floor never rises above info, so audit entries are written whatever the configured level is.
+type LevelController struct {
+	base   zapcore.Level   // The level from the configuration file
+	global zap.AtomicLevel // The effective level of the server-wide logger
+	floor  zap.AtomicLevel // The level the shared core is built at
+
+	core     zapcore.Core // The unfiltered core, captured when the logger is built
+	logger   *zap.Logger  // The server-wide logger
+	override atomic.Pointer[LevelOverride]
+	mutex    sync.Mutex // Serialises changes to the override and its timer
+	timer    *time.Timer
+}
+
+// LevelOverride describes a temporary change of the log level.
+type LevelOverride struct {
+	Level     zapcore.Level `json:"level"`
+	Identity  string        `json:"identity,omitempty"` // Empty when the override applies to every request
+	ExpiresAt time.Time     `json:"expires_at"`
+
+	logger *zap.Logger // Logger for the scoped identity, nil for a global override
+}
+
+// NewLevelController creates a controller whose base level is the given level.
+func NewLevelController(base zapcore.Level) *LevelController {
+	return &LevelController{
+		base:   base,
+		global: zap.NewAtomicLevelAt(base),
+		floor:  zap.NewAtomicLevelAt(min(base, zapcore.InfoLevel)),
+	}
+}
+
+// Floor returns the level the shared zap core has to be built at.
+func (c *LevelController) Floor() zap.AtomicLevel {
+	return c.floor
+}
+
+// Wrap is meant to be passed to zap.WrapCore when the server-wide logger is built. It captures the
+// unfiltered core and filters it by the effective server-wide level.
+func (c *LevelController) Wrap(core zapcore.Core) zapcore.Core {
+	c.core = core
+	return &levelCore{Core: core, level: c.global}
+}
+
+// Attach stores the server-wide logger built with Wrap, from which scoped loggers are derived.
+func (c *LevelController) Attach(logger *zap.Logger) {
+	c.logger = logger
+}
+
+// Logger returns the logger to use for a request made by the given client identity. Unless an
+// override is scoped to that identity, this is the server-wide logger.
+func (c *LevelController) Logger(identity string) *zap.Logger {
+	if o := c.override.Load(); o != nil && o.logger != nil && o.Identity == identity {
+		return o.logger
+	}
+	return c.logger
+}
+
+// AuditLogger returns a logger that always records info level entries, regardless of overrides.
+func (c *LevelController) AuditLogger() *zap.Logger {
+	return c.derive(zap.NewAtomicLevelAt(zapcore.InfoLevel)).Named("audit")
+}
+
+// Base returns the configured level.
+func (c *LevelController) Base() zapcore.Level {
+	return c.base
+}
+
+// Override returns the active override, or nil when the base level is in effect.
+func (c *LevelController) Override() *LevelOverride {
+	return c.override.Load()
+}
+
+// SetOverride changes the level for the given duration, after which the base level is restored.
+// A non-empty identity restricts the change to requests made by that client. Setting an override
+// replaces any override that is already active.
+func (c *LevelController) SetOverride(level zapcore.Level, identity string, duration time.Duration) (*LevelOverride, error) {
+	if duration <= 0 {
+		return nil, errors.New("override duration must be positive")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	o := &LevelOverride{
+		Level:     level,
+		Identity:  identity,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if identity != "" {
+		o.logger = c.derive(zap.NewAtomicLevelAt(level)).With(zap.String("log_level_override", identity))
+	}
+
+	c.apply(o)
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(duration, func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		// Only revert the override this timer was started for.
+		if c.override.Load() == o {
+			c.apply(nil)
+			c.logger.Info("Log level override expired", zap.Stringer("level", c.base))
+		}
+	})
+
+	return o, nil
+}
+
+// ClearOverride restores the base level immediately. It reports whether an override was active.
+func (c *LevelController) ClearOverride() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	active := c.override.Load() != nil
+	c.apply(nil)
+	return active
+}
+
+// apply makes o the active override and recomputes the effective levels. It must be called with
+// the mutex held.
+func (c *LevelController) apply(o *LevelOverride) {
+	global, floor := c.base, min(c.base, zapcore.InfoLevel)
+	if o != nil {
+		if o.Identity == "" {
+			global = o.Level
+		}
+		if o.Level < floor {
+			floor = o.Level
+		}
+	}
+
+	// Lower the floor before publishing the new levels and raise it afterwards, so no entry that
+	// should be written is dropped by the shared core in between.
+	if floor < c.floor.Level() {
+		c.floor.SetLevel(floor)
+	}
+	c.global.SetLevel(global)
+	c.override.Store(o)
+	c.floor.SetLevel(floor)
+}
+
+// derive returns a copy of the server-wide logger whose entries are filtered by level instead.
+func (c *LevelController) derive(level zap.AtomicLevel) *zap.Logger {
+	return c.logger.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return &levelCore{Core: c.core, level: level}
+	}))
+}
+
+// levelCore filters the entries of the wrapped core by its own level.
+type levelCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return c.level.Enabled(l) && c.Core.Enabled(l)
+}
+
+func (c *levelCore) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
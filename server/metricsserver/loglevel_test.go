@@ -0,0 +1,77 @@
+package metricsserver
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+	"time"
+)
+
+func newObservedLevelController(base zapcore.Level) (*LevelController, *observer.ObservedLogs) {
+	levels := NewLevelController(base)
+	core, logs := observer.New(levels.Floor())
+	levels.Attach(zap.New(core, zap.WrapCore(levels.Wrap)))
+	return levels, logs
+}
+
+func TestLevelController_GlobalOverride(t *testing.T) {
+	levels, logs := newObservedLevelController(zapcore.InfoLevel)
+
+	levels.Logger("client").Debug("dropped")
+	_, err := levels.SetOverride(zapcore.DebugLevel, "", time.Minute)
+	assert.NoError(t, err)
+	levels.Logger("client").Debug("written")
+	levels.Logger("other").Debug("written")
+
+	assert.True(t, levels.ClearOverride())
+	levels.Logger("client").Debug("dropped")
+
+	assert.Equal(t, 2, logs.FilterMessage("written").Len())
+	assert.Equal(t, 0, logs.FilterMessage("dropped").Len())
+}
+
+func TestLevelController_ScopedOverride(t *testing.T) {
+	levels, logs := newObservedLevelController(zapcore.InfoLevel)
+
+	_, err := levels.SetOverride(zapcore.DebugLevel, "client", time.Minute)
+	assert.NoError(t, err)
+	levels.Logger("client").Debug("scoped")
+	levels.Logger("other").Debug("unscoped")
+
+	assert.Equal(t, 1, logs.FilterMessage("scoped").Len())
+	assert.Equal(t, 0, logs.FilterMessage("unscoped").Len())
+}
+
+func TestLevelController_OverrideExpires(t *testing.T) {
+	levels, logs := newObservedLevelController(zapcore.InfoLevel)
+
+	_, err := levels.SetOverride(zapcore.DebugLevel, "", 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return levels.Override() == nil
+	}, time.Second, 5*time.Millisecond)
+
+	levels.Logger("client").Debug("dropped")
+	assert.Equal(t, 0, logs.FilterMessage("dropped").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Log level override expired").Len())
+}
+
+func TestLevelController_AuditLoggerIgnoresBaseLevel(t *testing.T) {
+	levels, logs := newObservedLevelController(zapcore.ErrorLevel)
+
+	levels.Logger("").Info("dropped")
+	levels.AuditLogger().Info("audited")
+
+	assert.Equal(t, 0, logs.FilterMessage("dropped").Len())
+	assert.Equal(t, 1, logs.FilterMessage("audited").Len())
+}
+
+func TestLevelController_RejectsNonPositiveDuration(t *testing.T) {
+	levels, _ := newObservedLevelController(zapcore.InfoLevel)
+
+	_, err := levels.SetOverride(zapcore.DebugLevel, "", 0)
+	assert.Error(t, err)
+	assert.Nil(t, levels.Override())
+}
@@ -0,0 +1,129 @@
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"math"
+	"runtime"
+	"runtime/debug"
+	runtimemetrics "runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const mebibyte = 1 << 20
+
+// defaultSoftLimitRatio derives the soft limit from the hard limit when none is configured.
+const defaultSoftLimitRatio = 0.8
+
+// MemoryLimitConfig configures the memory limiter.
+type MemoryLimitConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+	SoftLimitMiB  uint64        `mapstructure:"soft_limit_mib"` // Export calls are refused above it, 80% of the hard limit if 0
+	HardLimitMiB  uint64        `mapstructure:"hard_limit_mib"` // Garbage collection is forced above it, GOMEMLIMIT if 0
+	SetGoMemLimit bool          `mapstructure:"set_gomemlimit"` // Set GOMEMLIMIT to the hard limit when it is not set
+	RetryAfter    time.Duration `mapstructure:"retry_after"`    // Delay suggested to refused clients
+}
+
+// memoryLimiter protects the process from running out of memory under a burst of large exports.
+//
+// It checks the size of the heap periodically. Above the soft limit, new Export calls are refused
+// with a retryable status until the heap shrinks again. Above the hard limit, a garbage collection
+// is forced on top of that.
+type memoryLimiter struct {
+	logger     *zap.Logger
+	metrics    *serverMetrics
+	soft       uint64
+	hard       uint64
+	retryAfter time.Duration
+	heapSize   func() uint64
+	refusing   atomic.Bool
+}
+
+func newMemoryLimiter(logger *zap.Logger, config MemoryLimitConfig, metrics *serverMetrics) (*memoryLimiter, error) {
+	if config.CheckInterval <= 0 {
+		return nil, fmt.Errorf("check_interval must be positive, got %s", config.CheckInterval)
+	}
+
+	// GOMEMLIMIT serves as the hard limit when none is configured. Otherwise the hard limit can
+	// serve as GOMEMLIMIT, so the garbage collector works harder before the limiter has to step in.
+	hard := config.HardLimitMiB * mebibyte
+	goMemLimit := debug.SetMemoryLimit(-1)
+	switch {
+	case hard == 0 && goMemLimit == math.MaxInt64:
+		return nil, errors.New("hard_limit_mib is required unless GOMEMLIMIT is set")
+	case hard == 0:
+		hard = uint64(goMemLimit)
+	case goMemLimit == math.MaxInt64 && config.SetGoMemLimit:
+		debug.SetMemoryLimit(int64(hard))
+		logger.Info("Set GOMEMLIMIT to the hard memory limit", zap.Uint64("limit_bytes", hard))
+	}
+
+	soft := config.SoftLimitMiB * mebibyte
+	if soft == 0 {
+		soft = uint64(float64(hard) * defaultSoftLimitRatio)
+	}
+	if soft >= hard {
+		return nil, fmt.Errorf("the soft limit of %d MiB must be below the hard limit of %d MiB", soft/mebibyte, hard/mebibyte)
+	}
+
+	metrics.memoryLimit.WithLabelValues("soft").Set(float64(soft))
+	metrics.memoryLimit.WithLabelValues("hard").Set(float64(hard))
+	return &memoryLimiter{
+		logger:     logger,
+		metrics:    metrics,
+		soft:       soft,
+		hard:       hard,
+		retryAfter: config.RetryAfter,
+		heapSize:   heapSize,
+	}, nil
+}
+
+// check measures the heap, forcing a garbage collection above the hard limit, and updates whether
+// Export calls are refused. It fails while they are, so the server is reported as not ready.
+func (m *memoryLimiter) check(context.Context) error {
+	size := m.heapSize()
+	if size >= m.hard {
+		m.logger.Warn("Heap above the hard memory limit, forcing garbage collection",
+			zap.Uint64("heap_bytes", size), zap.Uint64("limit_bytes", m.hard))
+		m.metrics.memoryForcedGC.Inc()
+		runtime.GC()
+		size = m.heapSize()
+	}
+
+	refusing := size >= m.soft
+	m.refusing.Store(refusing)
+	m.metrics.memoryHeap.Set(float64(size))
+	if !refusing {
+		m.metrics.memoryRefusing.Set(0)
+		return nil
+	}
+	m.metrics.memoryRefusing.Set(1)
+	return fmt.Errorf("heap of %d MiB is above the soft limit of %d MiB", size/mebibyte, m.soft/mebibyte)
+}
+
+// heapSize returns the size of the heap objects, live or not yet swept.
+func heapSize() uint64 {
+	sample := []runtimemetrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	runtimemetrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+// UnaryInterceptorMemoryLimit returns a gRPC unary interceptor that refuses Export calls with
+// UNAVAILABLE and a RetryInfo detail while the heap is above the soft limit. Other methods are
+// not refused.
+func UnaryInterceptorMemoryLimit(m *memoryLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := req.(*pb.ExportMetricsServiceRequest); ok && m.refusing.Load() {
+			m.metrics.memoryRefused.Inc()
+			return nil, retryAfterError(codes.Unavailable, "memory usage is above the limit", m.retryAfter)
+		}
+		return handler(ctx, req)
+	}
+}
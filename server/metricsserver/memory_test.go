@@ -0,0 +1,92 @@
+package metricsserver
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"testing"
+	"time"
+)
+
+func newTestMemoryLimiter(t *testing.T, heapSizes ...uint64) *memoryLimiter {
+	m, err := newMemoryLimiter(zap.NewNop(), MemoryLimitConfig{
+		CheckInterval: time.Second,
+		SoftLimitMiB:  80,
+		HardLimitMiB:  100,
+		RetryAfter:    time.Second,
+	}, newServerMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.heapSize = func() uint64 {
+		size := heapSizes[0]
+		heapSizes = heapSizes[1:]
+		return size
+	}
+	return m
+}
+
+func TestMemoryLimiter_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		heapSizes    []uint64
+		wantRefusing bool
+		wantForcedGC float64
+	}{
+		{"below soft limit", []uint64{50 * mebibyte}, false, 0},
+		{"above soft limit", []uint64{90 * mebibyte}, true, 0},
+		{"above hard limit, collected", []uint64{120 * mebibyte, 60 * mebibyte}, false, 1},
+		{"above hard limit, still above soft limit", []uint64{120 * mebibyte, 95 * mebibyte}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMemoryLimiter(t, tt.heapSizes...)
+			err := m.check(context.Background())
+			assert.Equal(t, tt.wantRefusing, err != nil)
+			assert.Equal(t, tt.wantRefusing, m.refusing.Load())
+			assert.Equal(t, tt.wantForcedGC, testutil.ToFloat64(m.metrics.memoryForcedGC))
+		})
+	}
+}
+
+func TestUnaryInterceptorMemoryLimit(t *testing.T) {
+	m := newTestMemoryLimiter(t, 90*mebibyte)
+	assert.Error(t, m.check(context.Background()))
+
+	interceptor := UnaryInterceptorMemoryLimit(m)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Unary"}
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	_, err := interceptor(context.Background(), &pb.ExportMetricsServiceRequest{}, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Len(t, status.Convert(err).Details(), 1)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.metrics.memoryRefused))
+
+	resp, err := interceptor(context.Background(), &emptypb.Empty{}, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func TestNewMemoryLimiter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MemoryLimitConfig
+	}{
+		{"no check interval", MemoryLimitConfig{HardLimitMiB: 100}},
+		{"soft limit above hard limit", MemoryLimitConfig{CheckInterval: time.Second, SoftLimitMiB: 200, HardLimitMiB: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newMemoryLimiter(zap.NewNop(), tt.config, newServerMetrics())
+			assert.Error(t, err)
+		})
+	}
+}
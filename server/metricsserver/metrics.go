@@ -0,0 +1,337 @@
+package metricsserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// serverMetrics holds the Prometheus metrics of a server.
+type serverMetrics struct {
+	requestCount           *prometheus.CounterVec
+	requestDuration        *prometheus.HistogramVec
+	streamMessagesReceived *prometheus.HistogramVec
+	streamMessagesSent     *prometheus.HistogramVec
+	panicsRecovered        *prometheus.CounterVec
+	listenerConnections    *prometheus.GaugeVec
+
+	admissionInFlight  prometheus.Gauge
+	admissionLimit     prometheus.Gauge
+	admissionQueued    prometheus.Gauge
+	admissionQueueWait prometheus.Histogram
+	admissionShed      *prometheus.CounterVec
+
+	memoryHeap     prometheus.Gauge
+	memoryLimit    *prometheus.GaugeVec
+	memoryRefusing prometheus.Gauge
+	memoryRefused  prometheus.Counter
+	memoryForcedGC prometheus.Counter
+
+	healthCondition *prometheus.GaugeVec
+	serviceReady    *prometheus.GaugeVec
+
+	receivedResources  prometheus.Counter
+	receivedScopes     prometheus.Counter
+	receivedMetrics    *prometheus.CounterVec
+	receivedDataPoints *prometheus.CounterVec
+	rejectedDataPoints *prometheus.CounterVec
+
+	requestResources  prometheus.Histogram
+	requestScopes     prometheus.Histogram
+	requestMetrics    prometheus.Histogram
+	requestDataPoints prometheus.Histogram
+	requestSize       prometheus.Histogram
+
+	identityRequestBytes *prometheus.CounterVec
+	identityDataPoints   *prometheus.CounterVec
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		requestCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_request_count",
+				Help: "Total number of gRPC requests",
+			},
+			[]string{"method", "client", "code", "listener"},
+		),
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "grpc_request_duration_seconds",
+				Help:    "Duration of gRPC requests in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method", "client", "code", "listener"},
+		),
+		streamMessagesReceived: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "grpc_stream_messages_received",
+				Help:    "Number of messages received per gRPC stream",
+				Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+			},
+			[]string{"method"},
+		),
+		streamMessagesSent: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "grpc_stream_messages_sent",
+				Help:    "Number of messages sent per gRPC stream",
+				Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+			},
+			[]string{"method"},
+		),
+		panicsRecovered: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_panics_recovered_total",
+				Help: "Total number of panics recovered from in gRPC handlers",
+			},
+			[]string{"method"},
+		),
+		listenerConnections: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "grpc_listener_connections",
+				Help: "Number of open connections per gRPC listener",
+			},
+			[]string{"listener"},
+		),
+
+		admissionInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "grpc_admission_in_flight",
+				Help: "Number of Export calls being processed",
+			},
+		),
+		admissionLimit: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "grpc_admission_limit",
+				Help: "Current limit of Export calls processed concurrently",
+			},
+		),
+		admissionQueued: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "grpc_admission_queued",
+				Help: "Number of Export calls waiting for the concurrency limit",
+			},
+		),
+		admissionQueueWait: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "grpc_admission_queue_wait_seconds",
+				Help:    "Time Export calls waited for the concurrency limit in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+			},
+		),
+		admissionShed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_admission_shed_total",
+				Help: "Total number of Export calls rejected by admission control, by reason",
+			},
+			[]string{"reason"},
+		),
+
+		memoryHeap: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "server_memory_heap_bytes",
+				Help: "Size of the heap at the last check of the memory limiter in bytes",
+			},
+		),
+		memoryLimit: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "server_memory_limit_bytes",
+				Help: "Soft and hard heap limits of the memory limiter in bytes",
+			},
+			[]string{"limit"},
+		),
+		memoryRefusing: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "server_memory_refusing",
+				Help: "Whether Export calls are refused because the heap is above the soft limit (1) or not (0)",
+			},
+		),
+		memoryRefused: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "server_memory_refused_total",
+				Help: "Total number of Export calls refused because the heap was above the soft limit",
+			},
+		),
+		memoryForcedGC: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "server_memory_forced_gc_total",
+				Help: "Total number of garbage collections forced because the heap was above the hard limit",
+			},
+		),
+
+		healthCondition: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "server_health_condition",
+				Help: "Whether a readiness condition of the server is met (1) or not (0)",
+			},
+			[]string{"condition"},
+		),
+		serviceReady: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "server_ready",
+				Help: "Whether a gRPC service is ready to serve (1) or not (0), the empty service being the whole server",
+			},
+			[]string{"service"},
+		),
+
+		receivedResources: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "otlp_received_resources_total",
+				Help: "Total number of resources received in export requests",
+			},
+		),
+		receivedScopes: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "otlp_received_scopes_total",
+				Help: "Total number of instrumentation scopes received in export requests",
+			},
+		),
+		receivedMetrics: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "otlp_received_metrics_total",
+				Help: "Total number of metrics received in export requests, by OTLP metric type",
+			},
+			[]string{"type"},
+		),
+		receivedDataPoints: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "otlp_received_data_points_total",
+				Help: "Total number of data points received in export requests, by OTLP point type",
+			},
+			[]string{"type"},
+		),
+		rejectedDataPoints: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "otlp_rejected_data_points_total",
+				Help: "Total number of data points rejected by validation, by reason",
+			},
+			[]string{"reason"},
+		),
+
+		requestResources: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "otlp_request_resources",
+				Help:    "Number of resources per export request",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+			},
+		),
+		requestScopes: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "otlp_request_scopes",
+				Help:    "Number of instrumentation scopes per export request",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+			},
+		),
+		requestMetrics: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "otlp_request_metrics",
+				Help:    "Number of metrics per export request",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+			},
+		),
+		requestDataPoints: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "otlp_request_data_points",
+				Help:    "Number of data points per export request",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+			},
+		),
+		requestSize: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "otlp_request_size_bytes",
+				Help:    "Size of the encoded export requests in bytes",
+				Buckets: prometheus.ExponentialBuckets(256, 4, 9), // 256B to 16MiB
+			},
+		),
+
+		identityRequestBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "otlp_identity_received_bytes_total",
+				Help: "Total size of the export requests received from each client identity in bytes",
+			},
+			[]string{"identity"},
+		),
+		identityDataPoints: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "otlp_identity_received_data_points_total",
+				Help: "Total number of data points received from each client identity",
+			},
+			[]string{"identity"},
+		),
+	}
+}
+
+// newRegistry creates a registry with the Go runtime and process collectors that Prometheus's
+// default registry would otherwise provide.
+func newRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// register registers the metrics with the registry the server exports.
+func (m *serverMetrics) register(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{
+		m.requestCount,
+		m.requestDuration,
+		m.streamMessagesReceived,
+		m.streamMessagesSent,
+		m.panicsRecovered,
+		m.listenerConnections,
+		m.admissionInFlight,
+		m.admissionLimit,
+		m.admissionQueued,
+		m.admissionQueueWait,
+		m.admissionShed,
+		m.memoryHeap,
+		m.memoryLimit,
+		m.memoryRefusing,
+		m.memoryRefused,
+		m.memoryForcedGC,
+		m.healthCondition,
+		m.serviceReady,
+		m.receivedResources,
+		m.receivedScopes,
+		m.receivedMetrics,
+		m.receivedDataPoints,
+		m.rejectedDataPoints,
+		m.requestResources,
+		m.requestScopes,
+		m.requestMetrics,
+		m.requestDataPoints,
+		m.requestSize,
+		m.identityRequestBytes,
+		m.identityDataPoints,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordIngestion records the volume of an export request received from the given identity.
+func (m *serverMetrics) recordIngestion(identity string, stats requestStats) {
+	m.receivedResources.Add(float64(stats.resources))
+	m.receivedScopes.Add(float64(stats.scopes))
+	for metricType, count := range stats.metrics {
+		m.receivedMetrics.WithLabelValues(metricType).Add(float64(count))
+	}
+	for metricType, count := range stats.dataPoints {
+		m.receivedDataPoints.WithLabelValues(metricType).Add(float64(count))
+	}
+	for reason, count := range stats.rejected {
+		m.rejectedDataPoints.WithLabelValues(reason).Add(float64(count))
+	}
+
+	m.requestResources.Observe(float64(stats.resources))
+	m.requestScopes.Observe(float64(stats.scopes))
+	m.requestMetrics.Observe(float64(stats.totalMetrics()))
+	m.requestDataPoints.Observe(float64(stats.totalDataPoints()))
+	m.requestSize.Observe(float64(stats.bytes))
+
+	m.identityRequestBytes.WithLabelValues(identity).Add(float64(stats.bytes))
+	m.identityDataPoints.WithLabelValues(identity).Add(float64(stats.totalDataPoints()))
+}
@@ -0,0 +1,188 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/tls"
+	grpcrecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// unknownIdentity is reported for callers that did not present a verified client certificate.
+const unknownIdentity = "unknown"
+
+// clientIdentity returns the identity of the caller, which is the common name of the verified
+// client certificate of its connection.
+func clientIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return unknownIdentity
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return unknownIdentity
+	}
+	return identityFromTLS(&tlsInfo.State)
+}
+
+// identityFromTLS returns the common name of the verified client certificate of a TLS connection.
+func identityFromTLS(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return unknownIdentity
+	}
+	if cn := state.VerifiedChains[0][0].Subject.CommonName; cn != "" {
+		return cn
+	}
+	return unknownIdentity
+}
+
+// UnaryInterceptorPrometheus returns a gRPC unary interceptor that collects metrics
+// related to incoming unary RPC requests and records them using Prometheus.
+//
+// The interceptor derives the client label from the peer information in the context, using the
+// given labeler, which bounds the number of distinct label values.
+// It measures the duration of the RPC call processing and records metrics using Prometheus.
+// The recorded metrics include request count and duration, labeled with method name,
+// client, status code and the listener the request was received on.
+//
+// Example usage:
+//
+//	// Register the interceptor with your gRPC server.
+//	s := grpc.NewServer(
+//	    grpc.UnaryInterceptor(UnaryInterceptorPrometheus(metrics, clientLabels)),
+//	)
+//
+//	// Start your gRPC server.
+//	if err := s.Serve(lis); err != nil {
+//	    log.Fatalf("failed to serve: %v", err)
+//	}
+//
+//	// Ensure that your Prometheus metrics are registered for scraping.
+//	metrics.register(registry)
+//
+// This function is typically used as a gRPC server interceptor to monitor and measure
+// the performance of unary RPC calls in your gRPC server.
+func UnaryInterceptorPrometheus(m *serverMetrics, labels *clientLabeler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := labels.label(ctx)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start).Seconds()
+
+		code := status.Code(err).String()
+
+		// https://prometheus.io/docs/prometheus/latest/getting_started/
+		// Record the metrics
+		listener := ListenerFromContext(ctx)
+		m.requestCount.WithLabelValues(info.FullMethod, client, code, listener).Inc()
+		observeWithTraceExemplar(ctx, m.requestDuration.WithLabelValues(info.FullMethod, client, code, listener), duration)
+
+		return resp, err
+	}
+}
+
+// StreamInterceptorPrometheus is the streaming counterpart of UnaryInterceptorPrometheus.
+//
+// It records the request count and duration of every stream with the same labels as unary calls,
+// where the duration covers the whole lifetime of the stream. In addition, it records the number
+// of messages received and sent on each stream.
+func StreamInterceptorPrometheus(m *serverMetrics, labels *clientLabeler) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		client := labels.label(ss.Context())
+		stream := newMonitoredServerStream(ss)
+
+		start := time.Now()
+		err := handler(srv, stream)
+		duration := time.Since(start).Seconds()
+
+		code := status.Code(err).String()
+
+		listener := ListenerFromContext(ss.Context())
+		m.requestCount.WithLabelValues(info.FullMethod, client, code, listener).Inc()
+		observeWithTraceExemplar(ss.Context(), m.requestDuration.WithLabelValues(info.FullMethod, client, code, listener), duration)
+		m.streamMessagesReceived.WithLabelValues(info.FullMethod).Observe(float64(stream.received.Load()))
+		m.streamMessagesSent.WithLabelValues(info.FullMethod).Observe(float64(stream.sent.Load()))
+
+		return err
+	}
+}
+
+// recoveryHandler returns a handler for the recovery interceptors that logs a recovered panic
+// together with the stack of the panicking goroutine, and turns it into an Internal error.
+func recoveryHandler(logger *zap.Logger, m *serverMetrics) grpcrecovery.RecoveryHandlerFuncContext {
+	return func(ctx context.Context, p interface{}) error {
+		method, _ := grpc.Method(ctx)
+		m.panicsRecovered.WithLabelValues(method).Inc()
+		logger.Error("Recovered from panic", append([]zap.Field{
+			zap.String("method", method),
+			zap.String("request_id", requestIDFromContext(ctx)),
+			zap.String("identity", clientIdentity(ctx)),
+			zap.Any("panic", p),
+			zap.ByteString("stack", debug.Stack()),
+		}, traceFields(ctx)...)...)
+		return status.Errorf(codes.Internal, "internal error")
+	}
+}
+
+// observeWithTraceExemplar records an observation. When the request is part of a sampled trace, its
+// trace ID is attached to the observation as an exemplar.
+func observeWithTraceExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	sc := trace.SpanContextFromContext(ctx)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && sc.IsSampled() {
+		exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": sc.TraceID().String()})
+		return
+	}
+	observer.Observe(value)
+}
+
+// peerAddress returns the address of the caller, or "unknown" if it is not available.
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	return p.Addr.String()
+}
+
+// monitoredServerStream wraps a grpc.ServerStream to count the messages received and sent on it.
+// It can also replace the context of the stream.
+type monitoredServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	received atomic.Int64
+	sent     atomic.Int64
+}
+
+func newMonitoredServerStream(ss grpc.ServerStream) *monitoredServerStream {
+	return &monitoredServerStream{ServerStream: ss, ctx: ss.Context()}
+}
+
+func (s *monitoredServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *monitoredServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received.Add(1)
+	}
+	return err
+}
+
+func (s *monitoredServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package metricsserver
+
+import (
+	"context"
+	grpcrecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"testing"
+)
+
+// fakeServerStream is a grpc.ServerStream that delivers a fixed number of messages.
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	pending int
+	header  metadata.MD
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) RecvMsg(interface{}) error {
+	if s.pending == 0 {
+		return io.EOF
+	}
+	s.pending--
+	return nil
+}
+
+func (s *fakeServerStream) SendMsg(interface{}) error {
+	return nil
+}
+
+// echoHandler receives every message of the stream and sends one message back for each.
+func echoHandler(_ interface{}, stream grpc.ServerStream) error {
+	for {
+		if err := stream.RecvMsg(nil); err != nil {
+			return nil
+		}
+		if err := stream.SendMsg(nil); err != nil {
+			return err
+		}
+	}
+}
+
+func TestMonitoredServerStream(t *testing.T) {
+	stream := newMonitoredServerStream(&fakeServerStream{ctx: context.Background(), pending: 3})
+
+	assert.NoError(t, echoHandler(nil, stream))
+	assert.Equal(t, int64(3), stream.received.Load())
+	assert.Equal(t, int64(3), stream.sent.Load())
+}
+
+func TestRecoveryHandler(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	interceptor := grpcrecovery.UnaryServerInterceptor(
+		grpcrecovery.WithRecoveryHandlerContext(recoveryHandler(zap.New(core), newServerMetrics())),
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Unary"}
+
+	_, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	entries := logs.FilterMessage("Recovered from panic").All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "boom", entries[0].ContextMap()["panic"])
+		assert.Contains(t, entries[0].ContextMap()["stack"], "TestRecoveryHandler")
+	}
+}
@@ -0,0 +1,215 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"net"
+	"time"
+)
+
+// Option configures a Server created by New.
+type Option func(*options)
+
+type options struct {
+	logger          *zap.Logger
+	levels          *LevelController
+	tlsConfig       *tls.Config
+	registry        *prometheus.Registry
+	storage         Storage
+	address         string
+	listener        net.Listener
+	listeners       []ListenerConfig
+	metricsAddress  string
+	admin           AdminConfig
+	admission       AdmissionConfig
+	memoryLimit     MemoryLimitConfig
+	clientLabels    ClientLabelConfig
+	accessLog       AccessLogConfig
+	tracing         TracingConfig
+	health          HealthConfig
+	readinessChecks []readinessCheck
+	shutdown        ShutdownConfig
+	interceptors    []grpc.UnaryServerInterceptor
+}
+
+// readinessCheck is a readiness condition added with WithReadinessCheck.
+type readinessCheck struct {
+	name     string
+	check    func(context.Context) error
+	services []string
+}
+
+func defaultOptions() options {
+	return options{
+		logger:         zap.NewNop(),
+		address:        ":8080",
+		metricsAddress: ":9091",
+		admin: AdminConfig{
+			Address:     ":9092",
+			MaxOverride: time.Hour,
+		},
+		clientLabels: DefaultClientLabelConfig,
+		accessLog: AccessLogConfig{
+			Enabled:           true,
+			Level:             "info",
+			ErrorLevel:        "warn",
+			SuccessSampleRate: 1,
+			ErrorSampleRate:   1,
+		},
+		health: HealthConfig{
+			CheckInterval: 10 * time.Second,
+		},
+		shutdown: ShutdownConfig{
+			GracePeriod:  15 * time.Second,
+			FlushTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// WithLogger sets the logger of the server. Nothing is logged by default.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLevelController sets the controller of the log level, which allows the level to be changed
+// at runtime through the admin API. The logger passed to WithLogger must have been built with it.
+func WithLevelController(levels *LevelController) Option {
+	return func(o *options) {
+		o.levels = levels
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the gRPC listeners and the admin API. Without it,
+// only plaintext listeners on loopback addresses or Unix sockets can be served, and the admin API
+// cannot be enabled.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithRegistry sets the registry the metrics of the server are registered with and served from.
+// By default the server creates its own registry, which also holds the Go runtime and process
+// collectors.
+func WithRegistry(registry *prometheus.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithStorage sets where the export requests are kept. By default the last 10 successful and
+// partially rejected requests are kept in memory.
+func WithStorage(storage Storage) Option {
+	return func(o *options) {
+		o.storage = storage
+	}
+}
+
+// WithAddress sets the address of the default gRPC listener. It defaults to ":8080". The listener
+// uses mutual TLS when a TLS configuration is set, and is plaintext otherwise.
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// WithListener makes the default gRPC listener serve on the listener instead of listening on its
+// address, e.g. on an in-memory listener in tests. The server closes the listener when it stops.
+func WithListener(listener net.Listener) Option {
+	return func(o *options) {
+		o.listener = listener
+	}
+}
+
+// WithListeners replaces the default gRPC listener with the listeners, each served with its own
+// TLS mode, keepalive parameters and message size limit.
+func WithListeners(listeners ...ListenerConfig) Option {
+	return func(o *options) {
+		o.listeners = listeners
+	}
+}
+
+// WithMetricsAddress sets the TCP address serving the Prometheus metrics and the health probes.
+// It defaults to ":9091".
+func WithMetricsAddress(address string) Option {
+	return func(o *options) {
+		o.metricsAddress = address
+	}
+}
+
+// WithAdmin configures the admin API, which is disabled by default.
+func WithAdmin(config AdminConfig) Option {
+	return func(o *options) {
+		o.admin = config
+	}
+}
+
+// WithAdmission configures the limit of concurrent Export calls, which is disabled by default.
+func WithAdmission(config AdmissionConfig) Option {
+	return func(o *options) {
+		o.admission = config
+	}
+}
+
+// WithMemoryLimit configures the memory limiter, which is disabled by default.
+func WithMemoryLimit(config MemoryLimitConfig) Option {
+	return func(o *options) {
+		o.memoryLimit = config
+	}
+}
+
+// WithClientLabels configures how the client label of the request metrics is derived.
+func WithClientLabels(config ClientLabelConfig) Option {
+	return func(o *options) {
+		o.clientLabels = config
+	}
+}
+
+// WithAccessLog configures the access log, which logs every request at info level by default.
+func WithAccessLog(config AccessLogConfig) Option {
+	return func(o *options) {
+		o.accessLog = config
+	}
+}
+
+// WithTracing configures the tracing of the requests, which is disabled by default.
+func WithTracing(config TracingConfig) Option {
+	return func(o *options) {
+		o.tracing = config
+	}
+}
+
+// WithHealth configures the readiness checks.
+func WithHealth(config HealthConfig) Option {
+	return func(o *options) {
+		o.health = config
+	}
+}
+
+// WithReadinessCheck adds a readiness condition evaluated by calling check at every check
+// interval. The services limit the condition to the readiness of those gRPC services.
+func WithReadinessCheck(name string, check func(context.Context) error, services ...string) Option {
+	return func(o *options) {
+		o.readinessChecks = append(o.readinessChecks, readinessCheck{name, check, services})
+	}
+}
+
+// WithShutdown configures the phases of Stop.
+func WithShutdown(config ShutdownConfig) Option {
+	return func(o *options) {
+		o.shutdown = config
+	}
+}
+
+// WithUnaryInterceptor adds interceptors to the unary RPCs of the gRPC server. They run after the
+// built-in metrics and access log interceptors, so what they do is observed like any request.
+func WithUnaryInterceptor(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
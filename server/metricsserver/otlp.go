@@ -0,0 +1,101 @@
+package metricsserver
+
+import (
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// Names of the OTLP metric types, used as metric label values.
+const (
+	metricTypeGauge                = "gauge"
+	metricTypeSum                  = "sum"
+	metricTypeHistogram            = "histogram"
+	metricTypeExponentialHistogram = "exponential_histogram"
+	metricTypeSummary              = "summary"
+	metricTypeNone                 = "none"
+)
+
+// Reasons for which a metric is rejected by validation.
+const (
+	rejectMissingName        = "missing_name"
+	rejectMissingDescription = "missing_description"
+	rejectMissingUnit        = "missing_unit"
+	rejectMissingData        = "missing_data"
+)
+
+// requestStats summarises the content of an export request.
+type requestStats struct {
+	bytes      int
+	resources  int
+	scopes     int
+	metrics    map[string]int // Number of metrics by metric type
+	dataPoints map[string]int // Number of data points by metric type
+	rejected   map[string]int // Number of rejected data points by reason
+}
+
+func newRequestStats(bytes int) requestStats {
+	return requestStats{
+		bytes:      bytes,
+		metrics:    make(map[string]int),
+		dataPoints: make(map[string]int),
+		rejected:   make(map[string]int),
+	}
+}
+
+// addMetric counts a metric and its data points.
+func (s requestStats) addMetric(metric *v1.Metric) {
+	metricType, points := metricDataPoints(metric)
+	s.metrics[metricType]++
+	s.dataPoints[metricType] += points
+}
+
+func (s requestStats) totalMetrics() int {
+	total := 0
+	for _, count := range s.metrics {
+		total += count
+	}
+	return total
+}
+
+func (s requestStats) totalDataPoints() int {
+	total := 0
+	for _, count := range s.dataPoints {
+		total += count
+	}
+	return total
+}
+
+// metricDataPoints returns the type of a metric and the number of data points it carries.
+func metricDataPoints(metric *v1.Metric) (string, int) {
+	switch data := metric.Data.(type) {
+	case *v1.Metric_Gauge:
+		return metricTypeGauge, len(data.Gauge.GetDataPoints())
+	case *v1.Metric_Sum:
+		return metricTypeSum, len(data.Sum.GetDataPoints())
+	case *v1.Metric_Histogram:
+		return metricTypeHistogram, len(data.Histogram.GetDataPoints())
+	case *v1.Metric_ExponentialHistogram:
+		return metricTypeExponentialHistogram, len(data.ExponentialHistogram.GetDataPoints())
+	case *v1.Metric_Summary:
+		return metricTypeSummary, len(data.Summary.GetDataPoints())
+	default:
+		return metricTypeNone, 0
+	}
+}
+
+// validateMetric checks a metric and returns the reason for rejecting it, or an empty string if
+// the metric is valid.
+// Note: based on requirements, we can check a lot more cases for partial errors. Currently, there are some basic checks.
+func validateMetric(metric *v1.Metric) string {
+	switch {
+	case metric.Name == "":
+		return rejectMissingName
+	case metric.Description == "":
+		return rejectMissingDescription
+	case metric.Unit == "":
+		return rejectMissingUnit
+	case metric.Data == nil:
+		return rejectMissingData
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,117 @@
+package metricsserver
+
+import (
+	"github.com/stretchr/testify/assert"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"testing"
+)
+
+func TestMetricDataPoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		metric     *v1.Metric
+		wantType   string
+		wantPoints int
+	}{
+		{
+			name: "Gauge",
+			metric: &v1.Metric{Data: &v1.Metric_Gauge{Gauge: &v1.Gauge{
+				DataPoints: []*v1.NumberDataPoint{{}, {}},
+			}}},
+			wantType:   metricTypeGauge,
+			wantPoints: 2,
+		},
+		{
+			name: "Sum",
+			metric: &v1.Metric{Data: &v1.Metric_Sum{Sum: &v1.Sum{
+				DataPoints: []*v1.NumberDataPoint{{}},
+			}}},
+			wantType:   metricTypeSum,
+			wantPoints: 1,
+		},
+		{
+			name: "Histogram",
+			metric: &v1.Metric{Data: &v1.Metric_Histogram{Histogram: &v1.Histogram{
+				DataPoints: []*v1.HistogramDataPoint{{}, {}, {}},
+			}}},
+			wantType:   metricTypeHistogram,
+			wantPoints: 3,
+		},
+		{
+			name: "Exponential Histogram",
+			metric: &v1.Metric{Data: &v1.Metric_ExponentialHistogram{ExponentialHistogram: &v1.ExponentialHistogram{
+				DataPoints: []*v1.ExponentialHistogramDataPoint{{}},
+			}}},
+			wantType:   metricTypeExponentialHistogram,
+			wantPoints: 1,
+		},
+		{
+			name: "Summary",
+			metric: &v1.Metric{Data: &v1.Metric_Summary{Summary: &v1.Summary{
+				DataPoints: []*v1.SummaryDataPoint{{}},
+			}}},
+			wantType:   metricTypeSummary,
+			wantPoints: 1,
+		},
+		{
+			name:       "Empty Sum",
+			metric:     &v1.Metric{Data: &v1.Metric_Sum{}},
+			wantType:   metricTypeSum,
+			wantPoints: 0,
+		},
+		{
+			name:       "No Data",
+			metric:     &v1.Metric{},
+			wantType:   metricTypeNone,
+			wantPoints: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotPoints := metricDataPoints(tt.metric)
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Equal(t, tt.wantPoints, gotPoints)
+		})
+	}
+}
+
+func TestValidateMetric(t *testing.T) {
+	tests := []struct {
+		name       string
+		metric     *v1.Metric
+		wantReason string
+	}{
+		{
+			name:       "Valid",
+			metric:     &v1.Metric{Name: "metric1", Description: "desc1", Unit: "unit1", Data: &v1.Metric_Sum{}},
+			wantReason: "",
+		},
+		{
+			name:       "Missing Name",
+			metric:     &v1.Metric{Description: "desc1", Unit: "unit1", Data: &v1.Metric_Sum{}},
+			wantReason: rejectMissingName,
+		},
+		{
+			name:       "Missing Description",
+			metric:     &v1.Metric{Name: "metric1", Unit: "unit1", Data: &v1.Metric_Sum{}},
+			wantReason: rejectMissingDescription,
+		},
+		{
+			name:       "Missing Unit",
+			metric:     &v1.Metric{Name: "metric1", Description: "desc1", Data: &v1.Metric_Sum{}},
+			wantReason: rejectMissingUnit,
+		},
+		{
+			name:       "Missing Data",
+			metric:     &v1.Metric{Name: "metric1", Description: "desc1", Unit: "unit1"},
+			wantReason: rejectMissingData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantReason, validateMetric(tt.metric))
+		})
+	}
+}
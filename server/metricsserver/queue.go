@@ -0,0 +1,99 @@
+package metricsserver
+
+import (
+	"context"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"sync"
+	"time"
+)
+
+// Storage keeps the export requests handled by the server.
+type Storage interface {
+	// Store keeps an export request once it has been validated. Partial reports whether some of
+	// its data points were rejected. An error fails the request with UNAVAILABLE, so the client
+	// retries it.
+	Store(ctx context.Context, request CachedRequest, partial bool) error
+}
+
+// RequestCache is the default Storage of the server. It keeps the most recent successful and
+// partially rejected requests in two circular queues.
+type RequestCache struct {
+	lastSuccessfulRequests *CircularQueue
+	lastErrorRequests      *CircularQueue
+}
+
+// NewRequestCache creates a cache that keeps the last size successful and partially rejected
+// requests.
+func NewRequestCache(size int) *RequestCache {
+	return &RequestCache{
+		lastSuccessfulRequests: NewCircularQueue(size),
+		lastErrorRequests:      NewCircularQueue(size),
+	}
+}
+
+func (c *RequestCache) Store(_ context.Context, request CachedRequest, partial bool) error {
+	if partial {
+		c.lastErrorRequests.Enqueue(request)
+	} else {
+		c.lastSuccessfulRequests.Enqueue(request)
+	}
+	return nil
+}
+
+// Occupancy returns the number of cached requests and how many the cache can hold.
+func (c *RequestCache) Occupancy() (used, capacity int) {
+	return c.lastSuccessfulRequests.Len() + c.lastErrorRequests.Len(),
+		c.lastSuccessfulRequests.Cap() + c.lastErrorRequests.Cap()
+}
+
+// CachedRequest is an export request along with the ID and the time it was received with.
+type CachedRequest struct {
+	Request   *pb.ExportMetricsServiceRequest
+	RequestID string
+	Timestamp time.Time
+}
+
+// CircularQueue is a thread-safe circular queue that holds CachedRequest elements
+type CircularQueue struct {
+	queue []CachedRequest // The queue slice holding the CachedRequests
+	size  int             // The size of the queue
+	head  int             // The index of the head of the queue
+	tail  int             // The index of the tail of the queue
+	mutex sync.Mutex      // A mutex to ensure thread-safety
+}
+
+func NewCircularQueue(size int) *CircularQueue {
+	return &CircularQueue{
+		queue: make([]CachedRequest, size),
+		size:  size,
+	}
+}
+
+// Enqueue adds a new CachedRequest to the queue
+// If the queue is full, it will overwrite the oldest element
+func (q *CircularQueue) Enqueue(request CachedRequest) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	// Adjust head if the queue is full
+	if (q.tail+1)%q.size == q.head {
+		// Queue is full, dequeue one element
+		q.head = (q.head + 1) % q.size
+	}
+
+	q.queue[q.tail] = request
+	q.tail = (q.tail + 1) % q.size
+}
+
+// Len returns the number of elements in the queue
+func (q *CircularQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return (q.tail - q.head + q.size) % q.size
+}
+
+// Cap returns the number of elements the queue can hold, which is one less than its size as an
+// empty slot tells a full queue from an empty one
+func (q *CircularQueue) Cap() int {
+	return q.size - 1
+}
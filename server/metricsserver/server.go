@@ -0,0 +1,434 @@
+// Package metricsserver implements the metrics server: an OTLP metrics receiver served over gRPC,
+// together with its Prometheus metrics, health probes and admin API.
+//
+// A server is created with New, configured through options, and run with Start and Stop:
+//
+//	srv, err := metricsserver.New(
+//	    metricsserver.WithLogger(logger),
+//	    metricsserver.WithTLSConfig(tlsConfig),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	if err := srv.Start(ctx); err != nil {
+//	    return err
+//	}
+//	defer srv.Stop(context.Background())
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpcrecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // Accept gzip-compressed requests
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+	"metrics/server/pb/pv"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// cacheSize is the number of requests kept by the default storage.
+const cacheSize = 10
+
+// Refer to doc: https://grpc.io/docs/guides/keepalive/
+// https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/server/main.go
+var kaep = keepalive.EnforcementPolicy{
+	MinTime:             5 * time.Second, // If a client pings more than once every 5 seconds, terminate the connection
+	PermitWithoutStream: true,            // Allow pings even when there are no active streams
+}
+
+// Refer to doc: https://grpc.io/docs/guides/keepalive/
+// https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/server/main.go
+var kasp = keepalive.ServerParameters{
+	MaxConnectionIdle:     15 * time.Second, // If a client is idle for 15 seconds, send a GOAWAY
+	MaxConnectionAge:      30 * time.Second, // If any connection is alive for more than 30 seconds, send a GOAWAY
+	MaxConnectionAgeGrace: 5 * time.Second,  // Allow 5 seconds for pending RPCs to complete before forcibly closing connections
+	Time:                  5 * time.Second,  // Ping the client if it is idle for 5 seconds to ensure the connection is still active
+	Timeout:               1 * time.Second,  // Wait 1 second for the ping ack before assuming the connection is dead
+}
+
+// Server is a metrics server. It serves the OTLP metrics service and the version and status
+// services over gRPC, the Prometheus metrics, the health probes and the status over HTTP and, when
+// enabled, the admin API.
+type Server struct {
+	options        options
+	logger         *zap.Logger
+	listeners      []*grpcListener
+	health         *healthTracker
+	memory         *memoryLimiter
+	tracerProvider *sdktrace.TracerProvider
+	metricsServer  *http.Server
+	adminServer    *http.Server
+
+	metricsListener net.Listener
+	adminListener   net.Listener
+
+	stopChecks context.CancelFunc // Stops the periodic readiness checks
+	stopOnce   sync.Once
+	done       chan struct{} // Closed once a gRPC listener stops serving
+	err        error         // Why the gRPC listener stopped serving, set before done is closed
+}
+
+// New creates a server configured by the options. The server does not listen until it is started.
+func New(opts ...Option) (*Server, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.admin.Enabled && o.tlsConfig == nil {
+		return nil, errors.New("the admin API requires a TLS configuration")
+	}
+	if o.admin.Enabled && o.levels == nil {
+		return nil, errors.New("the admin API requires a level controller")
+	}
+	if o.storage == nil {
+		o.storage = NewRequestCache(cacheSize)
+	}
+	if o.registry == nil {
+		o.registry = newRegistry()
+	}
+	listenerConfigs := o.listeners
+	if listenerConfigs == nil {
+		tlsMode := TLSModeMutual
+		if o.tlsConfig == nil {
+			tlsMode = TLSModePlaintext
+		}
+		listenerConfigs = []ListenerConfig{{Name: defaultListenerName, Address: o.address, TLS: tlsMode, Listener: o.listener}}
+	}
+	listenerConfigs, err := validateListeners(listenerConfigs, o.tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listener configuration: %w", err)
+	}
+
+	// Bound the cardinality of the client labels of the request and ingestion metrics.
+	clientLabels, err := newClientLabeler(o.clientLabels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client label configuration: %w", err)
+	}
+	identityLabels, err := newClientLabeler(ClientLabelConfig{
+		Strategy:  clientLabelIdentity,
+		MaxValues: o.clientLabels.MaxValues,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid client label configuration: %w", err)
+	}
+
+	metrics := newServerMetrics()
+	status := newStatusTracker(o.storage)
+
+	// Custom interceptors defined in middleware.go, status.go and accesslog.go
+	// The request ID comes first, so every interceptor and the handler see it.
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		UnaryInterceptorRequestID(o.logger),
+		UnaryInterceptorPrometheus(metrics, clientLabels),
+		UnaryInterceptorStatus(status),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		StreamInterceptorRequestID(o.logger),
+		StreamInterceptorPrometheus(metrics, clientLabels),
+	}
+	if o.accessLog.Enabled {
+		accessLog, err := newAccessLog(o.logger, o.accessLog)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access log configuration: %w", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, UnaryInterceptorLogging(accessLog))
+		streamInterceptors = append(streamInterceptors, StreamInterceptorLogging(accessLog))
+	}
+	// The memory limiter and admission control come after the metrics and the access log, so
+	// refused requests are counted and logged like any other.
+	var memory *memoryLimiter
+	if o.memoryLimit.Enabled {
+		if memory, err = newMemoryLimiter(o.logger, o.memoryLimit, metrics); err != nil {
+			return nil, fmt.Errorf("invalid memory limit configuration: %w", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, UnaryInterceptorMemoryLimit(memory))
+	}
+	if o.admission.Enabled {
+		limiter, err := newLimiter(o.admission, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admission configuration: %w", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, UnaryInterceptorAdmission(limiter))
+	}
+	unaryInterceptors = append(unaryInterceptors, o.interceptors...)
+	// Recovery interceptors to handle panics. They come last, so the interceptors above observe a
+	// recovered panic as an Internal error.
+	unaryInterceptors = append(unaryInterceptors, grpcmiddleware.ChainUnaryServer(
+		grpcrecovery.UnaryServerInterceptor(
+			grpcrecovery.WithRecoveryHandlerContext(recoveryHandler(o.logger, metrics)),
+		),
+	))
+	streamInterceptors = append(streamInterceptors, grpcmiddleware.ChainStreamServer(
+		grpcrecovery.StreamServerInterceptor(
+			grpcrecovery.WithRecoveryHandlerContext(recoveryHandler(o.logger, metrics)),
+		),
+	))
+
+	serverOptions := []grpc.ServerOption{
+		grpc.StatsHandler(status),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+
+	if err := metrics.register(o.registry); err != nil {
+		return nil, fmt.Errorf("failed to register metrics: %w", err)
+	}
+
+	s := &Server{
+		options: o,
+		logger:  o.logger,
+		memory:  memory,
+		done:    make(chan struct{}),
+	}
+
+	// Trace the handling of requests, continuing the traces of the clients.
+	if o.tracing.Enabled {
+		if s.tracerProvider, err = initTracing(context.Background(), o.tracing); err != nil {
+			return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+		}
+		serverOptions = append(serverOptions, grpc.StatsHandler(newTracingStatsHandler(s.tracerProvider)))
+	}
+
+	srv := &service{
+		logger:         o.logger,
+		levels:         o.levels,
+		storage:        o.storage,
+		metrics:        metrics,
+		identityLabels: identityLabels,
+		capabilities:   newCapabilities(listenerConfigs, o.admission, o.memoryLimit.Enabled),
+		status:         status,
+	}
+	// The standard health service, whose status follows the readiness conditions.
+	healthServer := health.NewServer()
+	s.health = newHealthTracker(o.logger, healthServer, metrics,
+		pb.MetricsService_ServiceDesc.ServiceName,
+		pv.VersionService_ServiceDesc.ServiceName,
+		pv.StatusService_ServiceDesc.ServiceName,
+	)
+
+	// Every listener is served by its own gRPC server, all of them sharing the services.
+	for _, c := range listenerConfigs {
+		grpcServer := grpc.NewServer(append(c.serverOptions(o.tlsConfig, metrics), serverOptions...)...)
+		pb.RegisterMetricsServiceServer(grpcServer, srv)
+		pv.RegisterVersionServiceServer(grpcServer, srv)
+		pv.RegisterStatusServiceServer(grpcServer, srv)
+		reflection.Register(grpcServer)
+		healthpb.RegisterHealthServer(grpcServer, healthServer)
+		s.listeners = append(s.listeners, &grpcListener{config: c, server: grpcServer})
+	}
+
+	// OpenMetrics is required to expose the trace exemplars of the request duration histogram.
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(o.registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	// Liveness and readiness probes.
+	mux.HandleFunc("/healthz", s.health.livenessHandler)
+	mux.HandleFunc("/readyz", s.health.readinessHandler)
+	// The status of the server, as returned by GetStatus.
+	mux.HandleFunc("/status", status.handler)
+	s.metricsServer = &http.Server{Handler: mux}
+
+	// The admin API shares the mTLS configuration of the gRPC server.
+	if o.admin.Enabled {
+		s.adminServer = &http.Server{
+			Handler:   newAdminHandler(o.admin, o.levels),
+			TLSConfig: o.tlsConfig,
+		}
+	}
+
+	return s, nil
+}
+
+// Start listens on the addresses of the server and serves in the background. The context only
+// bounds the setup of the listeners; the server keeps serving until Stop is called.
+func (s *Server) Start(ctx context.Context) (err error) {
+	var lc net.ListenConfig
+	var listeners []net.Listener
+	listen := func(address string) (net.Listener, error) {
+		l, err := lc.Listen(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		listeners = append(listeners, l)
+		return l, nil
+	}
+	defer func() {
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+		}
+	}()
+
+	for _, l := range s.listeners {
+		if l.listener = l.config.Listener; l.listener == nil {
+			if l.listener, err = l.config.listen(ctx, &lc); err != nil {
+				return err
+			}
+			listeners = append(listeners, l.listener)
+		}
+	}
+	if s.metricsListener, err = listen(s.options.metricsAddress); err != nil {
+		return err
+	}
+	if s.adminServer != nil {
+		if s.adminListener, err = listen(s.options.admin.Address); err != nil {
+			return err
+		}
+	}
+
+	checksCtx, stopChecks := context.WithCancel(context.WithoutCancel(ctx))
+	s.stopChecks = stopChecks
+	s.addReadinessChecks(checksCtx)
+
+	for _, l := range s.listeners {
+		go func() {
+			err := l.server.Serve(l.listener)
+			s.stopOnce.Do(func() {
+				s.err = err
+				close(s.done)
+			})
+		}()
+		s.logger.Info("Server is listening", zap.String("listener", l.config.Name),
+			zap.Stringer("address", l.listener.Addr()), zap.String("tls", l.config.TLS))
+	}
+	go func() {
+		if err := s.metricsServer.Serve(s.metricsListener); !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Metrics endpoint stopped", zap.Error(err))
+		}
+	}()
+	if s.adminServer != nil {
+		go func() {
+			if err := s.adminServer.ServeTLS(s.adminListener, "", ""); !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error("Admin API stopped", zap.Error(err))
+			}
+		}()
+		s.logger.Info("Admin API is listening", zap.Stringer("address", s.adminListener.Addr()))
+	}
+
+	s.logger.Info("Metrics endpoint is listening", zap.Stringer("address", s.metricsListener.Addr()))
+	return nil
+}
+
+// addReadinessChecks registers the readiness conditions of the server, which are evaluated until
+// the context is done.
+func (s *Server) addReadinessChecks(ctx context.Context) {
+	interval := s.options.health.CheckInterval
+	if tlsConfig := s.options.tlsConfig; tlsConfig != nil && len(tlsConfig.Certificates) > 0 {
+		s.health.AddCheck(ctx, "certificates", interval, certificateCheck(tlsConfig.Certificates[0]))
+	}
+	// An unreachable trace collector loses traces, not metrics, so it is only reported.
+	if s.options.tracing.Enabled {
+		s.health.AddReportOnlyCheck(ctx, "trace_exporter", interval, reachableCheck(s.options.tracing.Endpoint))
+	}
+	// The memory limiter is checked at its own interval. While it refuses exports, the metrics
+	// service is not ready.
+	if s.memory != nil {
+		s.health.AddCheck(ctx, "memory", s.options.memoryLimit.CheckInterval, s.memory.check,
+			pb.MetricsService_ServiceDesc.ServiceName)
+	}
+	for _, c := range s.options.readinessChecks {
+		s.health.AddCheck(ctx, c.name, interval, c.check, c.services...)
+	}
+}
+
+// Stop shuts the server down gracefully. It stops taking traffic, drains the in-flight requests,
+// then flushes what is still buffered. Each phase is bounded by its timeout in the shutdown
+// configuration, and all of them by the context.
+func (s *Server) Stop(ctx context.Context) error {
+	shutdownConfig := s.options.shutdown
+	grpcServers := make([]*grpc.Server, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		grpcServers = append(grpcServers, l.server)
+	}
+	httpServers := []*http.Server{s.metricsServer}
+	if s.adminServer != nil {
+		httpServers = append(httpServers, s.adminServer)
+	}
+
+	phases := []shutdownPhase{{
+		name:    "mark_not_ready",
+		timeout: shutdownConfig.DrainDelay + time.Second,
+		run:     markNotReady(s.health, shutdownConfig.DrainDelay),
+	}, {
+		name:    "stop_grpc",
+		timeout: shutdownConfig.GracePeriod,
+		run:     stopGRPCServers(grpcServers...),
+	}, {
+		name:    "stop_http",
+		timeout: shutdownConfig.FlushTimeout,
+		run:     stopHTTPServers(httpServers...),
+	}}
+	if s.tracerProvider != nil {
+		phases = append(phases, shutdownPhase{
+			name:    "flush_traces",
+			timeout: shutdownConfig.FlushTimeout,
+			run:     s.tracerProvider.Shutdown,
+		})
+	}
+
+	err := shutdown(ctx, s.logger, phases...)
+	if s.stopChecks != nil {
+		s.stopChecks()
+	}
+	return err
+}
+
+// Done returns a channel that is closed once the gRPC listeners stop serving, either because the
+// server was stopped or because serving one of them failed.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that made a gRPC listener stop serving, or nil if it was stopped by Stop.
+// It must only be called once Done is closed.
+func (s *Server) Err() error {
+	return s.err
+}
+
+// Addr returns the address of the first gRPC listener, or nil if the server is not started.
+func (s *Server) Addr() net.Addr {
+	return listenerAddr(s.listeners[0].listener)
+}
+
+// ListenerAddr returns the address of the gRPC listener with the name, or nil if the server is not
+// started or has no such listener.
+func (s *Server) ListenerAddr(name string) net.Addr {
+	for _, l := range s.listeners {
+		if l.config.Name == name {
+			return listenerAddr(l.listener)
+		}
+	}
+	return nil
+}
+
+// MetricsAddr returns the address serving the Prometheus metrics and the health probes, or nil if
+// the server is not started.
+func (s *Server) MetricsAddr() net.Addr {
+	return listenerAddr(s.metricsListener)
+}
+
+// AdminAddr returns the address of the admin API, or nil if the server is not started or the
+// admin API is disabled.
+func (s *Server) AdminAddr() net.Addr {
+	return listenerAddr(s.adminListener)
+}
+
+func listenerAddr(l net.Listener) net.Addr {
+	if l == nil {
+		return nil
+	}
+	return l.Addr()
+}
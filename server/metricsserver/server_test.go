@@ -0,0 +1,164 @@
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
+	"metrics/server/pb/pv"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingStorage keeps every stored request, or fails with err when it is set.
+type recordingStorage struct {
+	mutex    sync.Mutex
+	requests []CachedRequest
+	err      error
+}
+
+func (s *recordingStorage) Store(_ context.Context, request CachedRequest, _ bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.requests = append(s.requests, request)
+	return nil
+}
+
+func TestServer_StartStop(t *testing.T) {
+	storage := &recordingStorage{}
+	registry := prometheus.NewRegistry()
+	srv, err := New(
+		WithAddress("127.0.0.1:0"),
+		WithMetricsAddress("127.0.0.1:0"),
+		WithStorage(storage),
+		WithRegistry(registry),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Nil(t, srv.Addr())
+
+	assert.NoError(t, srv.Start(context.Background()))
+	assert.NotNil(t, srv.Addr())
+	assert.NotNil(t, srv.MetricsAddr())
+	assert.Nil(t, srv.AdminAddr())
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, err = pb.NewMetricsServiceClient(conn).Export(context.Background(), &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{{}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, storage.requests, 1)
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + srv.MetricsAddr().String() + "/readyz")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+	resp, err = client.Get("http://" + srv.MetricsAddr().String() + "/metrics")
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), "grpc_request_count")
+		resp.Body.Close()
+	}
+	resp, err = client.Get("http://" + srv.MetricsAddr().String() + "/status")
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		var status pv.StatusResponse
+		if assert.NoError(t, protojson.Unmarshal(body, &status)) {
+			assert.Equal(t, int64(1), status.GetTotals().GetRequestsAccepted())
+		}
+		resp.Body.Close()
+	}
+
+	assert.NoError(t, srv.Stop(context.Background()))
+	select {
+	case <-srv.Done():
+		assert.NoError(t, srv.Err())
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop serving")
+	}
+}
+
+func TestServer_StorageFailure(t *testing.T) {
+	storage := &recordingStorage{err: errors.New("disk full")}
+	srv, err := New(WithAddress("127.0.0.1:0"), WithMetricsAddress("127.0.0.1:0"), WithStorage(storage))
+	if !assert.NoError(t, err) || !assert.NoError(t, srv.Start(context.Background())) {
+		return
+	}
+	defer srv.Stop(context.Background())
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, err = pb.NewMetricsServiceClient(conn).Export(context.Background(), &pb.ExportMetricsServiceRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServer_RequestIDWithoutAccessLog(t *testing.T) {
+	storage := &recordingStorage{}
+	srv, err := New(
+		WithAddress("127.0.0.1:0"),
+		WithMetricsAddress("127.0.0.1:0"),
+		WithStorage(storage),
+		WithAccessLog(AccessLogConfig{}),
+	)
+	if !assert.NoError(t, err) || !assert.NoError(t, srv.Start(context.Background())) {
+		return
+	}
+	defer srv.Stop(context.Background())
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	var header metadata.MD
+	_, err = pb.NewMetricsServiceClient(conn).Export(context.Background(), &pb.ExportMetricsServiceRequest{}, grpc.Header(&header))
+	assert.NoError(t, err)
+
+	// The request ID is returned and stored without the access log.
+	if assert.Len(t, header.Get(requestIDHeader), 1) && assert.Len(t, storage.requests, 1) {
+		assert.Equal(t, header.Get(requestIDHeader)[0], storage.requests[0].RequestID)
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"admin without TLS", []Option{WithAdmin(AdminConfig{Enabled: true})}},
+		{"invalid client label strategy", []Option{WithClientLabels(ClientLabelConfig{Strategy: "name", MaxValues: 1})}},
+		{"invalid access log level", []Option{WithAccessLog(AccessLogConfig{Enabled: true, Level: "loud"})}},
+		{"plaintext on all interfaces", []Option{WithAddress(":8080")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(append([]Option{WithAddress("127.0.0.1:0")}, tt.opts...)...)
+			assert.Error(t, err)
+		})
+	}
+}
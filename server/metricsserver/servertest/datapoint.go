@@ -0,0 +1,124 @@
+package servertest
+
+import (
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/prototext"
+	"strconv"
+	"time"
+)
+
+// DataPoint is a data point of an export request, together with the metric, scope and resource it
+// belongs to. Attribute values are rendered as strings.
+type DataPoint struct {
+	Resource   map[string]string
+	Scope      string
+	Metric     string
+	Type       string // gauge, sum, histogram, exponential_histogram or summary
+	Attributes map[string]string
+	Time       time.Time
+	Value      float64 // Value of gauges and sums, sum of the observations of histograms and summaries
+	Count      uint64  // Number of observations of histograms and summaries
+}
+
+// HasAttributes reports whether the data point has all the attributes with the same values.
+func (p DataPoint) HasAttributes(attributes map[string]string) bool {
+	for key, value := range attributes {
+		if v, ok := p.Attributes[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Flatten returns the data points of an export request.
+func Flatten(req *pb.ExportMetricsServiceRequest) []DataPoint {
+	var points []DataPoint
+	for _, resourceMetrics := range req.GetResourceMetrics() {
+		resource := attributeMap(resourceMetrics.GetResource().GetAttributes())
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			for _, metric := range scopeMetrics.GetMetrics() {
+				base := DataPoint{
+					Resource: resource,
+					Scope:    scopeMetrics.GetScope().GetName(),
+					Metric:   metric.GetName(),
+				}
+				points = append(points, metricDataPoints(base, metric)...)
+			}
+		}
+	}
+	return points
+}
+
+// metricDataPoints returns the data points of a metric, filling in the fields of base.
+func metricDataPoints(base DataPoint, metric *v1.Metric) []DataPoint {
+	var points []DataPoint
+	add := func(metricType string, attributes []*commonpb.KeyValue, timeUnixNano uint64, value float64, count uint64) {
+		p := base
+		p.Type = metricType
+		p.Attributes = attributeMap(attributes)
+		p.Time = time.Unix(0, int64(timeUnixNano))
+		p.Value = value
+		p.Count = count
+		points = append(points, p)
+	}
+
+	switch data := metric.Data.(type) {
+	case *v1.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			add("gauge", dp.GetAttributes(), dp.GetTimeUnixNano(), numberValue(dp), 0)
+		}
+	case *v1.Metric_Sum:
+		for _, dp := range data.Sum.GetDataPoints() {
+			add("sum", dp.GetAttributes(), dp.GetTimeUnixNano(), numberValue(dp), 0)
+		}
+	case *v1.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			add("histogram", dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetSum(), dp.GetCount())
+		}
+	case *v1.Metric_ExponentialHistogram:
+		for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+			add("exponential_histogram", dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetSum(), dp.GetCount())
+		}
+	case *v1.Metric_Summary:
+		for _, dp := range data.Summary.GetDataPoints() {
+			add("summary", dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetSum(), dp.GetCount())
+		}
+	}
+	return points
+}
+
+func numberValue(dp *v1.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*v1.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+func attributeMap(attributes []*commonpb.KeyValue) map[string]string {
+	m := make(map[string]string, len(attributes))
+	for _, kv := range attributes {
+		m[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	return m
+}
+
+// anyValueString renders scalar attribute values like strconv does, and others in their protobuf
+// text form.
+func anyValueString(value *commonpb.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case nil:
+		return ""
+	default:
+		return prototext.Format(value)
+	}
+}
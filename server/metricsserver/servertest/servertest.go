@@ -0,0 +1,341 @@
+// Package servertest runs a metrics server in-process, for integration tests of exporters.
+//
+// The server is started on a loopback or in-memory listener with generated mTLS credentials. It
+// records every export request it receives, offers helpers to wait for and assert on the metrics
+// they carry, and can be told to fail or partially reject the following exports:
+//
+//	func TestExporter(t *testing.T) {
+//	    srv := servertest.NewServer(t)
+//	    exporter := newExporter(srv.Endpoint(), srv.ClientTLSConfig())
+//
+//	    exporter.Record("queue.size", 3, attribute.String("queue", "orders"))
+//
+//	    srv.AssertMetric(t, "queue.size", map[string]string{"queue": "orders"}, 3)
+//	}
+package servertest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"metrics/server/metricsserver"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultClientIdentity is the common name of the generated client certificate.
+const DefaultClientIdentity = "servertest-client"
+
+// Option configures a Server created by NewServer.
+type Option func(*config)
+
+type config struct {
+	bufconn        bool
+	clientIdentity string
+	waitTimeout    time.Duration
+	serverOptions  []metricsserver.Option
+}
+
+// WithBufconn serves gRPC on an in-memory listener instead of a loopback port. Clients have to
+// connect with DialOptions or Dial.
+func WithBufconn() Option {
+	return func(c *config) {
+		c.bufconn = true
+	}
+}
+
+// WithClientIdentity sets the common name of the generated client certificate.
+func WithClientIdentity(identity string) Option {
+	return func(c *config) {
+		c.clientIdentity = identity
+	}
+}
+
+// WithWaitTimeout sets how long the wait and assert helpers wait. It defaults to 5 seconds.
+func WithWaitTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.waitTimeout = timeout
+	}
+}
+
+// WithServerOptions passes options to the underlying metrics server, e.g. to set its logger.
+func WithServerOptions(opts ...metricsserver.Option) Option {
+	return func(c *config) {
+		c.serverOptions = append(c.serverOptions, opts...)
+	}
+}
+
+// Server is a metrics server running in the test process.
+type Server struct {
+	server      *metricsserver.Server
+	credentials *credentials
+	listener    *bufconn.Listener // nil when serving on a loopback port
+	waitTimeout time.Duration
+
+	mutex    sync.Mutex
+	requests []*pb.ExportMetricsServiceRequest
+	changed  chan struct{} // Closed and replaced whenever a request is recorded
+	err      error
+	partial  *pb.ExportMetricsPartialSuccess
+}
+
+// NewServer starts a metrics server, which is stopped when the test completes.
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	c := config{
+		clientIdentity: DefaultClientIdentity,
+		waitTimeout:    5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	creds, err := newCredentials(c.clientIdentity)
+	if err != nil {
+		t.Fatalf("servertest: failed to generate credentials: %v", err)
+	}
+	s := &Server{
+		credentials: creds,
+		waitTimeout: c.waitTimeout,
+		changed:     make(chan struct{}),
+	}
+
+	serverOptions := []metricsserver.Option{
+		metricsserver.WithTLSConfig(creds.serverTLSConfig()),
+		metricsserver.WithAddress("127.0.0.1:0"),
+		metricsserver.WithMetricsAddress("127.0.0.1:0"),
+		metricsserver.WithShutdown(metricsserver.ShutdownConfig{
+			GracePeriod:  time.Second,
+			FlushTimeout: time.Second,
+		}),
+		metricsserver.WithUnaryInterceptor(s.intercept),
+	}
+	if c.bufconn {
+		s.listener = bufconn.Listen(1024 * 1024)
+		serverOptions = append(serverOptions, metricsserver.WithListener(s.listener))
+	}
+	s.server, err = metricsserver.New(append(serverOptions, c.serverOptions...)...)
+	if err != nil {
+		t.Fatalf("servertest: failed to create server: %v", err)
+	}
+	if err := s.server.Start(context.Background()); err != nil {
+		t.Fatalf("servertest: failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		s.server.Stop(context.Background())
+	})
+
+	return s
+}
+
+// Endpoint returns the target clients connect to.
+func (s *Server) Endpoint() string {
+	if s.listener != nil {
+		return "passthrough:///bufconn"
+	}
+	return s.server.Addr().String()
+}
+
+// MetricsURL returns the URL of the Prometheus metrics of the server.
+func (s *Server) MetricsURL() string {
+	return "http://" + s.server.MetricsAddr().String() + "/metrics"
+}
+
+// ClientTLSConfig returns a TLS configuration that presents the generated client certificate and
+// trusts the server.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	return s.credentials.clientTLSConfig()
+}
+
+// DialOptions returns the options to connect a gRPC client to Endpoint.
+func (s *Server) DialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(grpccredentials.NewTLS(s.ClientTLSConfig())),
+	}
+	if s.listener != nil {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}))
+	}
+	return opts
+}
+
+// Dial connects a gRPC client to the server. The connection is closed when the test completes.
+func (s *Server) Dial(t testing.TB) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient(s.Endpoint(), s.DialOptions()...)
+	if err != nil {
+		t.Fatalf("servertest: failed to connect: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+// ReturnError makes the following exports fail with err, which should be a gRPC status error.
+// The requests are still recorded. Passing nil restores normal processing.
+func (s *Server) ReturnError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.err = err
+}
+
+// ReturnPartialSuccess makes the following exports report that the number of data points were
+// rejected for the reason in message. Passing 0 and an empty message restores normal processing.
+func (s *Server) ReturnPartialSuccess(rejected int64, message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.partial = nil
+	if rejected != 0 || message != "" {
+		s.partial = &pb.ExportMetricsPartialSuccess{RejectedDataPoints: rejected, ErrorMessage: message}
+	}
+}
+
+// Reset forgets the recorded requests and restores normal processing.
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = nil
+	s.err = nil
+	s.partial = nil
+}
+
+// Requests returns the export requests received so far, in the order they arrived.
+func (s *Server) Requests() []*pb.ExportMetricsServiceRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*pb.ExportMetricsServiceRequest(nil), s.requests...)
+}
+
+// DataPoints returns the data points of the export requests received so far.
+func (s *Server) DataPoints() []DataPoint {
+	var points []DataPoint
+	for _, req := range s.Requests() {
+		points = append(points, Flatten(req)...)
+	}
+	return points
+}
+
+// WaitForRequests waits until at least n export requests have been received and returns them. The
+// test fails if they do not arrive in time.
+func (s *Server) WaitForRequests(t testing.TB, n int) []*pb.ExportMetricsServiceRequest {
+	t.Helper()
+	var requests []*pb.ExportMetricsServiceRequest
+	s.waitFor(func() bool {
+		requests = s.Requests()
+		return len(requests) >= n
+	})
+	if len(requests) < n {
+		t.Fatalf("servertest: received %d export requests after %s, want %d", len(requests), s.waitTimeout, n)
+	}
+	return requests
+}
+
+// WaitForMetric waits until a data point of the named metric with the attributes has been
+// received and returns the latest one. The attributes only need to be a subset of those of the
+// data point. The test fails if no such data point arrives in time.
+func (s *Server) WaitForMetric(t testing.TB, name string, attributes map[string]string) DataPoint {
+	t.Helper()
+	var found []DataPoint
+	s.waitFor(func() bool {
+		found = s.find(name, attributes)
+		return len(found) > 0
+	})
+	if len(found) == 0 {
+		t.Fatalf("servertest: no data point of %s with attributes %v received after %s", name, attributes, s.waitTimeout)
+	}
+	return found[len(found)-1]
+}
+
+// AssertMetric waits until a data point of the named metric with the attributes and the value has
+// been received, and reports an error listing the values received instead if none arrives in
+// time. For histograms and summaries, the value is the sum of the observations.
+func (s *Server) AssertMetric(t testing.TB, name string, attributes map[string]string, value float64) bool {
+	t.Helper()
+	var found []DataPoint
+	ok := s.waitFor(func() bool {
+		found = s.find(name, attributes)
+		for _, p := range found {
+			if p.Value == value {
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		values := make([]string, len(found))
+		for i, p := range found {
+			values[i] = fmt.Sprint(p.Value)
+		}
+		t.Errorf("servertest: no data point of %s with attributes %v and value %v received after %s, got values [%s]",
+			name, attributes, value, s.waitTimeout, strings.Join(values, ", "))
+	}
+	return ok
+}
+
+// find returns the data points of the named metric with the attributes.
+func (s *Server) find(name string, attributes map[string]string) []DataPoint {
+	var found []DataPoint
+	for _, p := range s.DataPoints() {
+		if p.Metric == name && p.HasAttributes(attributes) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// waitFor evaluates the condition whenever a request is recorded, until it holds or the wait
+// timeout elapses. It reports whether the condition holds.
+func (s *Server) waitFor(condition func() bool) bool {
+	timeout := time.NewTimer(s.waitTimeout)
+	defer timeout.Stop()
+	for {
+		// Take the channel before evaluating the condition, so no request can be missed.
+		s.mutex.Lock()
+		changed := s.changed
+		s.mutex.Unlock()
+
+		if condition() {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return false
+		}
+	}
+}
+
+// intercept records the export requests and applies the injected error or partial success.
+func (s *Server) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	exportReq, ok := req.(*pb.ExportMetricsServiceRequest)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, proto.Clone(exportReq).(*pb.ExportMetricsServiceRequest))
+	close(s.changed)
+	s.changed = make(chan struct{})
+	err, partial := s.err, s.partial
+	s.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	if err == nil && partial != nil {
+		resp = &pb.ExportMetricsServiceResponse{PartialSuccess: partial}
+	}
+	return resp, err
+}
@@ -0,0 +1,145 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func gaugeRequest(name string, value float64, attributes ...*commonpb.KeyValue) *pb.ExportMetricsServiceRequest {
+	return &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{{
+			ScopeMetrics: []*v1.ScopeMetrics{{
+				Metrics: []*v1.Metric{{
+					Name:        name,
+					Description: "A test gauge",
+					Unit:        "1",
+					Data: &v1.Metric_Gauge{Gauge: &v1.Gauge{
+						DataPoints: []*v1.NumberDataPoint{{
+							Attributes: attributes,
+							Value:      &v1.NumberDataPoint_AsDouble{AsDouble: value},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestServer_AssertMetric(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"loopback", nil},
+		{"bufconn", []Option{WithBufconn()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(t, tt.opts...)
+			client := pb.NewMetricsServiceClient(srv.Dial(t))
+
+			go client.Export(context.Background(), gaugeRequest("queue.size", 3, stringAttribute("queue", "orders")))
+
+			assert.True(t, srv.AssertMetric(t, "queue.size", map[string]string{"queue": "orders"}, 3))
+			p := srv.WaitForMetric(t, "queue.size", nil)
+			assert.Equal(t, "gauge", p.Type)
+			assert.Len(t, srv.WaitForRequests(t, 1), 1)
+		})
+	}
+}
+
+// recordingTB records the errors reported to it instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors string
+}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors += fmt.Sprintf(format, args...)
+}
+
+func TestServer_AssertMetricFails(t *testing.T) {
+	srv := NewServer(t, WithWaitTimeout(50*time.Millisecond))
+	client := pb.NewMetricsServiceClient(srv.Dial(t))
+	_, err := client.Export(context.Background(), gaugeRequest("queue.size", 3))
+	assert.NoError(t, err)
+
+	inner := &recordingTB{TB: t}
+	assert.False(t, srv.AssertMetric(inner, "queue.size", nil, 4))
+	assert.Contains(t, inner.errors, "got values [3]")
+}
+
+func TestServer_InjectedResponses(t *testing.T) {
+	srv := NewServer(t)
+	client := pb.NewMetricsServiceClient(srv.Dial(t))
+	req := gaugeRequest("queue.size", 3)
+
+	srv.ReturnError(status.Error(codes.Unavailable, "overloaded"))
+	_, err := client.Export(context.Background(), req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	srv.ReturnError(nil)
+	srv.ReturnPartialSuccess(2, "dropped")
+	resp, err := client.Export(context.Background(), req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2), resp.GetPartialSuccess().GetRejectedDataPoints())
+		assert.Equal(t, "dropped", resp.GetPartialSuccess().GetErrorMessage())
+	}
+	assert.Len(t, srv.Requests(), 2)
+
+	srv.Reset()
+	resp, err = client.Export(context.Background(), req)
+	if assert.NoError(t, err) {
+		assert.Nil(t, resp.GetPartialSuccess())
+	}
+	assert.Len(t, srv.Requests(), 1)
+}
+
+func TestFlatten(t *testing.T) {
+	req := &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{{
+			ScopeMetrics: []*v1.ScopeMetrics{{
+				Metrics: []*v1.Metric{
+					{Name: "requests", Data: &v1.Metric_Sum{Sum: &v1.Sum{
+						DataPoints: []*v1.NumberDataPoint{{Value: &v1.NumberDataPoint_AsInt{AsInt: 7}}},
+					}}},
+					{Name: "latency", Data: &v1.Metric_Histogram{Histogram: &v1.Histogram{
+						DataPoints: []*v1.HistogramDataPoint{{Count: 2, Sum: proto64(1.5)}},
+					}}},
+				},
+			}},
+		}},
+	}
+
+	points := Flatten(req)
+	if assert.Len(t, points, 2) {
+		assert.Equal(t, DataPoint{Metric: "requests", Type: "sum", Value: 7}, withoutMaps(points[0]))
+		assert.Equal(t, DataPoint{Metric: "latency", Type: "histogram", Value: 1.5, Count: 2}, withoutMaps(points[1]))
+	}
+}
+
+func proto64(v float64) *float64 {
+	return &v
+}
+
+// withoutMaps clears the fields of a data point that are not comparable with a literal.
+func withoutMaps(p DataPoint) DataPoint {
+	p.Resource, p.Attributes, p.Time = nil, nil, time.Time{}
+	return p
+}
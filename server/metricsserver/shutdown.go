@@ -0,0 +1,119 @@
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShutdownConfig configures the phases of the graceful shutdown of the server.
+type ShutdownConfig struct {
+	DrainDelay   time.Duration `mapstructure:"drain_delay"`
+	GracePeriod  time.Duration `mapstructure:"grace_period"`
+	FlushTimeout time.Duration `mapstructure:"flush_timeout"`
+}
+
+// errShuttingDown is the state of the readiness condition set once the server starts shutting down.
+var errShuttingDown = errors.New("server is shutting down")
+
+// shutdownPhase is one step of the shutdown of the server.
+type shutdownPhase struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// shutdown runs the phases in order, each with its own deadline within the one of ctx, and logs
+// how long each phase took. A failing phase does not prevent the following ones from running.
+func shutdown(ctx context.Context, logger *zap.Logger, phases ...shutdownPhase) error {
+	start := time.Now()
+	logger.Info("Shutting down")
+
+	var errs []error
+	for _, phase := range phases {
+		phaseCtx, cancel := context.WithTimeout(ctx, phase.timeout)
+		phaseStart := time.Now()
+		err := phase.run(phaseCtx)
+		cancel()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", phase.name, err))
+			logger.Warn("Shutdown phase failed", zap.String("phase", phase.name),
+				zap.Duration("duration", time.Since(phaseStart)), zap.Error(err))
+		} else {
+			logger.Info("Shutdown phase completed", zap.String("phase", phase.name),
+				zap.Duration("duration", time.Since(phaseStart)))
+		}
+	}
+
+	logger.Info("Shutdown completed", zap.Duration("duration", time.Since(start)))
+	return errors.Join(errs...)
+}
+
+// markNotReady fails the readiness of the server, and then waits for the drain delay so load
+// balancers and the kubelet notice before the server stops accepting connections.
+func markNotReady(h *healthTracker, drainDelay time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		h.Shutdown()
+		select {
+		case <-time.After(drainDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// stopGRPCServers stops the servers gracefully, waiting for in-flight RPCs to complete. The servers
+// that do not complete them before the deadline are stopped forcefully, cancelling them.
+func stopGRPCServers(servers ...*grpc.Server) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		var forced atomic.Int32
+		for _, s := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stopped := make(chan struct{})
+				go func() {
+					s.GracefulStop()
+					close(stopped)
+				}()
+
+				select {
+				case <-stopped:
+				case <-ctx.Done():
+					s.Stop()
+					<-stopped
+					forced.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if forced.Load() > 0 {
+			return errors.New("in-flight RPCs did not complete in time and were cancelled")
+		}
+		return nil
+	}
+}
+
+// stopHTTPServers shuts the servers down, waiting for active requests to complete.
+func stopHTTPServers(servers ...*http.Server) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, server := range servers {
+			if err := server.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+				server.Close()
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,80 @@
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestShutdown_RunsAllPhases(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	var ran []string
+
+	err := shutdown(context.Background(), zap.New(core),
+		shutdownPhase{name: "first", timeout: time.Second, run: func(context.Context) error {
+			ran = append(ran, "first")
+			return errors.New("failed")
+		}},
+		shutdownPhase{name: "second", timeout: time.Second, run: func(context.Context) error {
+			ran = append(ran, "second")
+			return nil
+		}},
+	)
+
+	assert.EqualError(t, err, "first: failed")
+	assert.Equal(t, []string{"first", "second"}, ran)
+	assert.Equal(t, 1, logs.FilterMessage("Shutdown phase failed").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Shutdown phase completed").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Shutdown completed").Len())
+}
+
+func TestMarkNotReady(t *testing.T) {
+	grpcHealth := health.NewServer()
+	h := newHealthTracker(zap.NewNop(), grpcHealth, newServerMetrics(), "metrics")
+
+	assert.NoError(t, markNotReady(h, 0)(context.Background()))
+
+	ready, failing := h.Ready()
+	assert.False(t, ready)
+	assert.Contains(t, failing, "shutting_down")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, grpcHealth, "metrics"))
+}
+
+func TestStopGRPCServers_ForcesStopAfterDeadline(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	grpcHealth := health.NewServer()
+	healthpb.RegisterHealthServer(s, grpcHealth)
+	go s.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// A watch stream stays open until the client or the server ends it, so it blocks GracefulStop.
+	stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, stopGRPCServers(s)(ctx))
+
+	_, err = stream.Recv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,235 @@
+package metricsserver
+
+import (
+	"cmp"
+	"context"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"metrics/server/pb/pv"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The rates are computed from counts kept in buckets of 10 seconds, over at most 15 minutes.
+const (
+	rateBucketWidth = 10 * time.Second
+	rateBucketCount = 90
+)
+
+// maxRejectionReasons is the number of rejection reasons reported by the status.
+const maxRejectionReasons = 10
+
+// rateWindows are the windows the status reports rates over.
+var rateWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// ingestionCounts counts the Export calls and their data points.
+type ingestionCounts struct {
+	requestsAccepted   int64
+	requestsRejected   int64
+	dataPointsAccepted int64
+	dataPointsRejected int64
+}
+
+func (c *ingestionCounts) add(other ingestionCounts) {
+	c.requestsAccepted += other.requestsAccepted
+	c.requestsRejected += other.requestsRejected
+	c.dataPointsAccepted += other.dataPointsAccepted
+	c.dataPointsRejected += other.dataPointsRejected
+}
+
+// rateBucket holds the counts of the slot-th period of rateBucketWidth since the Unix epoch.
+type rateBucket struct {
+	slot   int64
+	counts ingestionCounts
+}
+
+// occupancyReporter is implemented by the storages that can tell how many requests they hold.
+type occupancyReporter interface {
+	Occupancy() (used, capacity int)
+}
+
+// statusTracker keeps the live statistics of the server reported by GetStatus and /status: the
+// ingestion totals and rates, the open connections by identity and the rejection reasons.
+type statusTracker struct {
+	startTime time.Time
+	storage   Storage
+
+	mutex       sync.Mutex
+	totals      ingestionCounts
+	buckets     [rateBucketCount]rateBucket
+	rejections  map[string]int64
+	connections map[string]int64
+}
+
+func newStatusTracker(storage Storage) *statusTracker {
+	return &statusTracker{
+		startTime:   time.Now(),
+		storage:     storage,
+		rejections:  make(map[string]int64),
+		connections: make(map[string]int64),
+	}
+}
+
+// record adds the counts and rejection reasons observed at the time.
+func (t *statusTracker) record(now time.Time, counts ingestionCounts, rejections map[string]int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.totals.add(counts)
+	slot := now.UnixNano() / int64(rateBucketWidth)
+	bucket := &t.buckets[slot%rateBucketCount]
+	if bucket.slot != slot {
+		*bucket = rateBucket{slot: slot}
+	}
+	bucket.counts.add(counts)
+	for reason, count := range rejections {
+		t.rejections[reason] += int64(count)
+	}
+}
+
+// recordRequest records the outcome of an Export call. Failed calls are counted as rejections
+// by status code.
+func (t *statusTracker) recordRequest(err error) {
+	if err == nil {
+		t.record(time.Now(), ingestionCounts{requestsAccepted: 1}, nil)
+		return
+	}
+	t.record(time.Now(), ingestionCounts{requestsRejected: 1}, map[string]int{status.Code(err).String(): 1})
+}
+
+// recordDataPoints records the data points of an export request and the reasons they were
+// rejected for.
+func (t *statusTracker) recordDataPoints(stats requestStats) {
+	var rejected int
+	for _, count := range stats.rejected {
+		rejected += count
+	}
+	t.record(time.Now(), ingestionCounts{
+		dataPointsAccepted: int64(max(stats.totalDataPoints()-rejected, 0)),
+		dataPointsRejected: int64(rejected),
+	}, stats.rejected)
+}
+
+// rates returns the per-second rates over the window. Until the server has been running for the
+// whole window, the rates are computed over its uptime.
+func (t *statusTracker) rates(now time.Time, window time.Duration) *pv.IngestionRates {
+	slot := now.UnixNano() / int64(rateBucketWidth)
+	oldest := slot - int64(window/rateBucketWidth)
+
+	var counts ingestionCounts
+	for _, bucket := range t.buckets {
+		if bucket.slot > oldest && bucket.slot <= slot {
+			counts.add(bucket.counts)
+		}
+	}
+
+	seconds := min(window, now.Sub(t.startTime)).Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	return &pv.IngestionRates{
+		Window:                      durationpb.New(window),
+		RequestsAcceptedPerSecond:   float64(counts.requestsAccepted) / seconds,
+		RequestsRejectedPerSecond:   float64(counts.requestsRejected) / seconds,
+		DataPointsAcceptedPerSecond: float64(counts.dataPointsAccepted) / seconds,
+		DataPointsRejectedPerSecond: float64(counts.dataPointsRejected) / seconds,
+	}
+}
+
+// status returns the status of the server at the time.
+func (t *statusTracker) status(now time.Time) *pv.StatusResponse {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	resp := &pv.StatusResponse{
+		StartTime: timestamppb.New(t.startTime),
+		Uptime:    durationpb.New(now.Sub(t.startTime)),
+		Totals: &pv.IngestionCounts{
+			RequestsAccepted:   t.totals.requestsAccepted,
+			RequestsRejected:   t.totals.requestsRejected,
+			DataPointsAccepted: t.totals.dataPointsAccepted,
+			DataPointsRejected: t.totals.dataPointsRejected,
+		},
+	}
+	for _, window := range rateWindows {
+		resp.Rates = append(resp.Rates, t.rates(now, window))
+	}
+	if reporter, ok := t.storage.(occupancyReporter); ok {
+		used, capacity := reporter.Occupancy()
+		resp.Cache = &pv.CacheOccupancy{Used: int64(used), Capacity: int64(capacity)}
+	}
+
+	for identity, count := range t.connections {
+		resp.Connections = append(resp.Connections, &pv.IdentityConnections{Identity: identity, Connections: count})
+	}
+	slices.SortFunc(resp.Connections, func(a, b *pv.IdentityConnections) int {
+		return cmp.Or(cmp.Compare(b.Connections, a.Connections), cmp.Compare(a.Identity, b.Identity))
+	})
+	for reason, count := range t.rejections {
+		resp.TopRejectionReasons = append(resp.TopRejectionReasons, &pv.RejectionReason{Reason: reason, Count: count})
+	}
+	slices.SortFunc(resp.TopRejectionReasons, func(a, b *pv.RejectionReason) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Reason, b.Reason))
+	})
+	if len(resp.TopRejectionReasons) > maxRejectionReasons {
+		resp.TopRejectionReasons = resp.TopRejectionReasons[:maxRejectionReasons]
+	}
+	return resp
+}
+
+// handler serves /status, the JSON encoding of the response of GetStatus.
+func (t *statusTracker) handler(w http.ResponseWriter, _ *http.Request) {
+	body, err := protojson.Marshal(t.status(time.Now()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// The status tracker is a gRPC stats handler counting the open connections by client identity.
+// The peer, and thus the identity, is known once the connection begins.
+
+func (t *statusTracker) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (t *statusTracker) HandleConn(ctx context.Context, s stats.ConnStats) {
+	identity := clientIdentity(ctx)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch s.(type) {
+	case *stats.ConnBegin:
+		t.connections[identity]++
+	case *stats.ConnEnd:
+		if t.connections[identity]--; t.connections[identity] <= 0 {
+			delete(t.connections, identity)
+		}
+	}
+}
+
+func (t *statusTracker) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (t *statusTracker) HandleRPC(context.Context, stats.RPCStats) {}
+
+// UnaryInterceptorStatus returns a gRPC unary interceptor that records the outcome of Export calls
+// in the status of the server.
+func UnaryInterceptorStatus(t *statusTracker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if _, ok := req.(*pb.ExportMetricsServiceRequest); ok {
+			t.recordRequest(err)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,175 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"metrics/server/pb/pv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// identityContext returns the context of an RPC from a client with a verified certificate.
+func identityContext(identity string) context.Context {
+	state := tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: identity}}}},
+	}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestStatusTracker_Rates(t *testing.T) {
+	tracker := newStatusTracker(nil)
+	start := time.Unix(1700000000, 0)
+	tracker.startTime = start.Add(-time.Hour)
+
+	// 60 accepted requests over the last minute, and 240 more before that.
+	tracker.record(start.Add(-10*time.Minute), ingestionCounts{requestsAccepted: 240}, nil)
+	tracker.record(start.Add(-30*time.Second), ingestionCounts{requestsAccepted: 60, dataPointsRejected: 6}, nil)
+	// Older than the longest window.
+	tracker.record(start.Add(-20*time.Minute), ingestionCounts{requestsAccepted: 1000}, nil)
+
+	resp := tracker.status(start)
+	assert.Equal(t, int64(1300), resp.GetTotals().GetRequestsAccepted())
+	if assert.Len(t, resp.GetRates(), 3) {
+		assert.Equal(t, time.Minute, resp.GetRates()[0].GetWindow().AsDuration())
+		assert.InDelta(t, 1.0, resp.GetRates()[0].GetRequestsAcceptedPerSecond(), 1e-9)
+		assert.InDelta(t, 0.1, resp.GetRates()[0].GetDataPointsRejectedPerSecond(), 1e-9)
+		assert.InDelta(t, 0.2, resp.GetRates()[1].GetRequestsAcceptedPerSecond(), 1e-9)
+		assert.InDelta(t, 300.0/900, resp.GetRates()[2].GetRequestsAcceptedPerSecond(), 1e-9)
+	}
+}
+
+func TestStatusTracker_RatesDuringFirstMinute(t *testing.T) {
+	tracker := newStatusTracker(nil)
+	now := tracker.startTime.Add(10 * time.Second)
+	tracker.record(now, ingestionCounts{requestsRejected: 20}, nil)
+
+	// Rates are computed over the uptime until it covers the window.
+	resp := tracker.status(now)
+	assert.InDelta(t, 2.0, resp.GetRates()[0].GetRequestsRejectedPerSecond(), 1e-9)
+}
+
+func TestStatusTracker_TopRejectionReasons(t *testing.T) {
+	tracker := newStatusTracker(nil)
+	stats := newRequestStats(0)
+	stats.dataPoints[metricTypeGauge] = 10
+	stats.rejected[rejectMissingUnit] = 3
+	stats.rejected[rejectMissingName] = 1
+	tracker.recordDataPoints(stats)
+	tracker.recordRequest(status.Error(codes.ResourceExhausted, "shed"))
+	tracker.recordRequest(nil)
+	for i := 0; i < maxRejectionReasons; i++ {
+		tracker.record(time.Now(), ingestionCounts{}, map[string]int{fmt.Sprintf("reason_%d", i): 1})
+	}
+
+	resp := tracker.status(time.Now())
+	assert.Equal(t, &pv.IngestionCounts{
+		RequestsAccepted:   1,
+		RequestsRejected:   1,
+		DataPointsAccepted: 6,
+		DataPointsRejected: 4,
+	}, resp.GetTotals())
+	if assert.Len(t, resp.GetTopRejectionReasons(), maxRejectionReasons) {
+		assert.Equal(t, rejectMissingUnit, resp.GetTopRejectionReasons()[0].GetReason())
+		assert.Equal(t, int64(3), resp.GetTopRejectionReasons()[0].GetCount())
+		assert.Equal(t, "ResourceExhausted", resp.GetTopRejectionReasons()[1].GetReason())
+	}
+}
+
+func TestStatusTracker_ExportDataPoints(t *testing.T) {
+	labels, _ := newClientLabeler(DefaultClientLabelConfig)
+	s := &service{
+		logger:         zap.NewNop(),
+		storage:        NewRequestCache(10),
+		metrics:        newServerMetrics(),
+		identityLabels: labels,
+		status:         newStatusTracker(nil),
+	}
+	req := &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*v1.ResourceMetrics{{
+			ScopeMetrics: []*v1.ScopeMetrics{{
+				Metrics: []*v1.Metric{
+					{Name: "rejected", Data: &v1.Metric_Sum{Sum: &v1.Sum{
+						DataPoints: []*v1.NumberDataPoint{{}, {}, {}},
+					}}},
+					{Name: "accepted", Description: "desc", Unit: "unit", Data: &v1.Metric_Gauge{Gauge: &v1.Gauge{
+						DataPoints: []*v1.NumberDataPoint{{}, {}},
+					}}},
+				},
+			}},
+		}},
+	}
+	_, err := s.Export(context.Background(), req)
+	assert.NoError(t, err)
+
+	// Every data point of the rejected metric is rejected, and only those of the other are accepted.
+	resp := s.status.status(time.Now())
+	assert.Equal(t, int64(2), resp.GetTotals().GetDataPointsAccepted())
+	assert.Equal(t, int64(3), resp.GetTotals().GetDataPointsRejected())
+	if assert.Len(t, resp.GetTopRejectionReasons(), 1) {
+		assert.Equal(t, rejectMissingDescription, resp.GetTopRejectionReasons()[0].GetReason())
+		assert.Equal(t, int64(3), resp.GetTopRejectionReasons()[0].GetCount())
+	}
+}
+
+func TestStatusTracker_Connections(t *testing.T) {
+	tracker := newStatusTracker(NewRequestCache(3))
+	alice := identityContext("alice")
+	bob := identityContext("bob")
+
+	tracker.HandleConn(alice, &stats.ConnBegin{})
+	tracker.HandleConn(bob, &stats.ConnBegin{})
+	tracker.HandleConn(bob, &stats.ConnBegin{})
+	tracker.HandleConn(alice, &stats.ConnEnd{})
+
+	resp := tracker.status(time.Now())
+	if assert.Len(t, resp.GetConnections(), 1) {
+		assert.Equal(t, "bob", resp.GetConnections()[0].GetIdentity())
+		assert.Equal(t, int64(2), resp.GetConnections()[0].GetConnections())
+	}
+	assert.Equal(t, int64(4), resp.GetCache().GetCapacity())
+}
+
+func TestUnaryInterceptorStatus(t *testing.T) {
+	tracker := newStatusTracker(nil)
+	interceptor := UnaryInterceptorStatus(tracker)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Unary"}
+	failing := func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("failed") }
+
+	interceptor(context.Background(), &pb.ExportMetricsServiceRequest{}, info, failing)
+	// Only Export calls are recorded.
+	interceptor(context.Background(), "not an export", info, failing)
+
+	totals := tracker.status(time.Now()).GetTotals()
+	assert.Equal(t, int64(1), totals.GetRequestsRejected())
+}
+
+func TestStatusTracker_Handler(t *testing.T) {
+	tracker := newStatusTracker(nil)
+	tracker.recordRequest(nil)
+
+	w := httptest.NewRecorder()
+	tracker.handler(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var resp pv.StatusResponse
+	if assert.NoError(t, protojson.Unmarshal(w.Body.Bytes(), &resp)) {
+		assert.Equal(t, int64(1), resp.GetTotals().GetRequestsAccepted())
+	}
+}
@@ -0,0 +1,197 @@
+package metricsserver
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"metrics/server/version"
+	"strings"
+	"time"
+)
+
+// TracingConfig configures the export of the spans of the server.
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+	ServiceName string  `mapstructure:"service_name"`
+}
+
+// instrumentationName identifies the tracer that creates the spans of the server.
+const instrumentationName = "metrics/server"
+
+// initTracing creates a tracer provider that exports the spans of the server over OTLP/gRPC. The
+// provider is not installed globally, so servers embedded in the same process do not interfere.
+//
+// The returned provider has to be shut down to flush the spans that are still buffered.
+func initTracing(ctx context.Context, config TracingConfig) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+		semconv.ServiceVersion(version.Version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	), nil
+}
+
+// startStage starts a span for a processing stage of a request. The span is created by the tracer
+// provider of the span of the request, so nothing is recorded for requests that are not traced.
+// The caller has to end the span.
+func startStage(ctx context.Context, name string) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+}
+
+// traceFields returns the zap fields identifying the span of a request, or nil if it is not traced.
+func traceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
+
+// tracingStatsHandler is a gRPC stats handler that creates a server span for every RPC.
+//
+// A stats handler is used rather than an interceptor because it observes the RPC from the moment
+// its headers arrive, so the span also covers receiving and decoding the request. That part is
+// recorded as a separate "decode" span. A W3C trace context sent by the client is continued.
+type tracingStatsHandler struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func newTracingStatsHandler(tp trace.TracerProvider) *tracingStatsHandler {
+	return &tracingStatsHandler{
+		tracer: tp.Tracer(instrumentationName),
+		propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	}
+}
+
+// rpcSpanKey is the context key of the rpcSpan of an RPC.
+type rpcSpanKey struct{}
+
+// rpcSpan holds the state of the server span of an RPC between stats events.
+type rpcSpan struct {
+	span         trace.Span
+	begin        time.Time
+	decodeTraced bool
+}
+
+func (h *tracingStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = h.propagator.Extract(ctx, metadataCarrier(md))
+
+	service, method := splitFullMethod(info.FullMethodName)
+	ctx, span := h.tracer.Start(ctx, strings.TrimPrefix(info.FullMethodName, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(method),
+			attribute.String("client.identity", clientIdentity(ctx)),
+			attribute.String("client.address", peerAddress(ctx)),
+		),
+	)
+	return context.WithValue(ctx, rpcSpanKey{}, &rpcSpan{span: span, begin: time.Now()})
+}
+
+func (h *tracingStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	rs, ok := ctx.Value(rpcSpanKey{}).(*rpcSpan)
+	if !ok {
+		return
+	}
+
+	switch s := s.(type) {
+	case *stats.InPayload:
+		// The payload event fires once the first message has been received and unmarshalled.
+		if !rs.decodeTraced {
+			rs.decodeTraced = true
+			_, decode := h.tracer.Start(ctx, "decode",
+				trace.WithTimestamp(rs.begin),
+				trace.WithAttributes(
+					attribute.Int("rpc.message.compressed_size", s.CompressedLength),
+					attribute.Int("rpc.message.uncompressed_size", s.Length),
+				))
+			decode.End(trace.WithTimestamp(s.RecvTime))
+		}
+	case *stats.End:
+		code := status.Code(s.Error)
+		rs.span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if s.Error != nil {
+			rs.span.SetStatus(otelcodes.Error, status.Convert(s.Error).Message())
+		}
+		rs.span.End(trace.WithTimestamp(s.EndTime))
+	}
+}
+
+func (h *tracingStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *tracingStatsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// splitFullMethod splits a gRPC method name of the form /package.Service/Method.
+func splitFullMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
+
+// metadataCarrier adapts gRPC metadata to the carrier interface of the OpenTelemetry propagators.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,57 @@
+package metricsserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestTracingStatsHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01",
+	))
+
+	h := newTracingStatsHandler(tp)
+	ctx = h.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"})
+	assert.Equal(t, traceID, trace.SpanContextFromContext(ctx).TraceID().String())
+	assert.Len(t, traceFields(ctx), 2)
+
+	h.HandleRPC(ctx, &stats.InPayload{RecvTime: time.Now(), Length: 10})
+	h.HandleRPC(ctx, &stats.InPayload{RecvTime: time.Now(), Length: 10})
+	h.HandleRPC(ctx, &stats.End{EndTime: time.Now(), Error: status.Error(codes.InvalidArgument, "bad request")})
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		decode, rpc := spans[0], spans[1]
+		assert.Equal(t, "decode", decode.Name())
+		assert.Equal(t, rpc.SpanContext().SpanID(), decode.Parent().SpanID())
+
+		assert.Equal(t, "opentelemetry.proto.collector.metrics.v1.MetricsService/Export", rpc.Name())
+		assert.Equal(t, trace.SpanKindServer, rpc.SpanKind())
+		assert.Equal(t, traceID, rpc.SpanContext().TraceID().String())
+		assert.True(t, rpc.Parent().IsRemote())
+		assert.Equal(t, "bad request", rpc.Status().Description)
+	}
+}
+
+func TestTraceFields_Untraced(t *testing.T) {
+	assert.Nil(t, traceFields(context.Background()))
+}
+
+func TestSplitFullMethod(t *testing.T) {
+	service, method := splitFullMethod("/main.VersionService/GetVersion")
+	assert.Equal(t, "main.VersionService", service)
+	assert.Equal(t, "GetVersion", method)
+}
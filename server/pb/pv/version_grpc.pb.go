@@ -20,7 +20,8 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	VersionService_GetVersion_FullMethodName = "/main.VersionService/GetVersion"
+	VersionService_GetVersion_FullMethodName      = "/main.VersionService/GetVersion"
+	VersionService_GetCapabilities_FullMethodName = "/main.VersionService/GetCapabilities"
 )
 
 // VersionServiceClient is the client API for VersionService service.
@@ -30,6 +31,9 @@ type VersionServiceClient interface {
 	// GetVersion retrieves the current version information.
 	// This method takes no parameters and returns a VersionResponse message containing version information such as the build timestamp and Git commit SHA.
 	GetVersion(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionResponse, error)
+	// GetCapabilities retrieves the capabilities of the server.
+	// This method takes no parameters and returns a CapabilitiesResponse message describing the supported encodings, compression, receivers, limits and validation policy.
+	GetCapabilities(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
 }
 
 type versionServiceClient struct {
@@ -49,6 +53,15 @@ func (c *versionServiceClient) GetVersion(ctx context.Context, in *emptypb.Empty
 	return out, nil
 }
 
+func (c *versionServiceClient) GetCapabilities(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, VersionService_GetCapabilities_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // VersionServiceServer is the server API for VersionService service.
 // All implementations must embed UnimplementedVersionServiceServer
 // for forward compatibility
@@ -56,6 +69,9 @@ type VersionServiceServer interface {
 	// GetVersion retrieves the current version information.
 	// This method takes no parameters and returns a VersionResponse message containing version information such as the build timestamp and Git commit SHA.
 	GetVersion(context.Context, *emptypb.Empty) (*VersionResponse, error)
+	// GetCapabilities retrieves the capabilities of the server.
+	// This method takes no parameters and returns a CapabilitiesResponse message describing the supported encodings, compression, receivers, limits and validation policy.
+	GetCapabilities(context.Context, *emptypb.Empty) (*CapabilitiesResponse, error)
 	mustEmbedUnimplementedVersionServiceServer()
 }
 
@@ -66,6 +82,9 @@ type UnimplementedVersionServiceServer struct {
 func (UnimplementedVersionServiceServer) GetVersion(context.Context, *emptypb.Empty) (*VersionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
 }
+func (UnimplementedVersionServiceServer) GetCapabilities(context.Context, *emptypb.Empty) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapabilities not implemented")
+}
 func (UnimplementedVersionServiceServer) mustEmbedUnimplementedVersionServiceServer() {}
 
 // UnsafeVersionServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -97,6 +116,24 @@ func _VersionService_GetVersion_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VersionService_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VersionServiceServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VersionService_GetCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VersionServiceServer).GetCapabilities(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // VersionService_ServiceDesc is the grpc.ServiceDesc for VersionService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -108,6 +145,10 @@ var VersionService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetVersion",
 			Handler:    _VersionService_GetVersion_Handler,
 		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _VersionService_GetCapabilities_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "version.proto",
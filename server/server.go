@@ -1,87 +1,95 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
-	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpcrecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/viper"
 	pb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/keepalive"
-	"google.golang.org/grpc/reflection"
 	"io/ioutil"
 	"log"
-	"metrics/server/pb/pv"
-	"net"
-	"net/http"
+	"metrics/server/metricsserver"
 	"os"
-	"sync"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
-const (
-	pathOfConfigFile = "./server/config.yaml"
-	cacheSize        = 10
-)
+const pathOfConfigFile = "./server/config.yaml"
+
+type Config struct {
+	LoggerConfig `mapstructure:",squash"`
+	Listeners    []metricsserver.ListenerConfig  `mapstructure:"listeners"`
+	Admin        metricsserver.AdminConfig       `mapstructure:"admin"`
+	Admission    metricsserver.AdmissionConfig   `mapstructure:"admission"`
+	MemoryLimit  metricsserver.MemoryLimitConfig `mapstructure:"memory_limit"`
+	Metrics      MetricsConfig                   `mapstructure:"metrics"`
+	Tracing      metricsserver.TracingConfig     `mapstructure:"tracing"`
+	AccessLog    metricsserver.AccessLogConfig   `mapstructure:"access_log"`
+	Health       metricsserver.HealthConfig      `mapstructure:"health"`
+	Shutdown     metricsserver.ShutdownConfig    `mapstructure:"shutdown"`
+}
 
-type server struct {
-	pb.UnimplementedMetricsServiceServer
-	pv.UnimplementedVersionServiceServer
-	lastSuccessfulRequests *CircularQueue
-	lastErrorRequests      *CircularQueue
-	cacheMutex             sync.Mutex
-	logger                 *zap.Logger
+type MetricsConfig struct {
+	ClientLabel metricsserver.ClientLabelConfig `mapstructure:"client_label"`
 }
 
 type LoggerConfig struct {
 	Level string `mapstructure:"level"`
 }
 
-// Refer to doc: https://grpc.io/docs/guides/keepalive/
-// https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/server/main.go
-var kaep = keepalive.EnforcementPolicy{
-	MinTime:             5 * time.Second, // If a client pings more than once every 5 seconds, terminate the connection
-	PermitWithoutStream: true,            // Allow pings even when there are no active streams
-}
-
-// Refer to doc: https://grpc.io/docs/guides/keepalive/
-// https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/server/main.go
-var kasp = keepalive.ServerParameters{
-	MaxConnectionIdle:     15 * time.Second, // If a client is idle for 15 seconds, send a GOAWAY
-	MaxConnectionAge:      30 * time.Second, // If any connection is alive for more than 30 seconds, send a GOAWAY
-	MaxConnectionAgeGrace: 5 * time.Second,  // Allow 5 seconds for pending RPCs to complete before forcibly closing connections
-	Time:                  5 * time.Second,  // Ping the client if it is idle for 5 seconds to ensure the connection is still active
-	Timeout:               1 * time.Second,  // Wait 1 second for the ping ack before assuming the connection is dead
-}
-
-func loadConfig(path string) (LoggerConfig, error) {
+func loadConfig(path string) (Config, error) {
 	// Load configuration from file
 	viper.SetConfigFile(path)
+	viper.SetDefault("admin.address", ":9092")
+	viper.SetDefault("admin.max_override", time.Hour)
+	viper.SetDefault("admission.max_in_flight", 256)
+	viper.SetDefault("admission.max_queued", 512)
+	viper.SetDefault("admission.max_queue_wait", 100*time.Millisecond)
+	viper.SetDefault("admission.retry_after", time.Second)
+	viper.SetDefault("admission.adaptive.min_limit", 32)
+	viper.SetDefault("admission.adaptive.max_limit", 1024)
+	viper.SetDefault("admission.adaptive.target_latency", 50*time.Millisecond)
+	viper.SetDefault("memory_limit.check_interval", time.Second)
+	viper.SetDefault("memory_limit.retry_after", time.Second)
+	viper.SetDefault("health.check_interval", 10*time.Second)
+	viper.SetDefault("shutdown.grace_period", 15*time.Second)
+	viper.SetDefault("shutdown.flush_timeout", 5*time.Second)
+	viper.SetDefault("access_log.enabled", true)
+	viper.SetDefault("access_log.level", "info")
+	viper.SetDefault("access_log.error_level", "warn")
+	viper.SetDefault("access_log.success_sample_rate", 1.0)
+	viper.SetDefault("access_log.error_sample_rate", 1.0)
+	viper.SetDefault("tracing.endpoint", "localhost:4317")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.service_name", "metrics-server")
+	viper.SetDefault("metrics.client_label.strategy", metricsserver.DefaultClientLabelConfig.Strategy)
+	viper.SetDefault("metrics.client_label.max_values", metricsserver.DefaultClientLabelConfig.MaxValues)
+	viper.SetDefault("metrics.client_label.hash_buckets", metricsserver.DefaultClientLabelConfig.HashBuckets)
 	if err := viper.ReadInConfig(); err != nil {
-		return LoggerConfig{}, err
+		return Config{}, err
 	}
 	// Unmarshal configuration into struct
-	var loggerConfig LoggerConfig
-	if err := viper.Unmarshal(&loggerConfig); err != nil {
-		return LoggerConfig{}, err
+	var config Config
+	if err := viper.Unmarshal(&config); err != nil {
+		return Config{}, err
 	}
 
-	return loggerConfig, nil
+	return config, nil
 }
 
-func initLogger(config LoggerConfig) (*zap.Logger, error) {
-	var level zap.AtomicLevel
+func initLogger(config LoggerConfig) (*zap.Logger, *metricsserver.LevelController, error) {
+	var level zapcore.Level
 	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	// The level controller allows the level to be changed at runtime through the admin API.
+	levels := metricsserver.NewLevelController(level)
 
 	cfg := zap.NewProductionConfig()
-	cfg.Level = level
+	cfg.Level = levels.Floor()
 	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder // Human-readable timestamps
 	cfg.OutputPaths = []string{"stdout", "./logs/server.log"}
 	cfg.ErrorOutputPaths = []string{"stderr"}
@@ -90,12 +98,16 @@ func initLogger(config LoggerConfig) (*zap.Logger, error) {
 		Thereafter: 100,
 	}
 
-	logger, err := cfg.Build(zap.AddCallerSkip(1)) // Skip the zap library's frames in the call stack
+	logger, err := cfg.Build(
+		zap.AddCallerSkip(1), // Skip the zap library's frames in the call stack
+		zap.WrapCore(levels.Wrap),
+	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	levels.Attach(logger)
 
-	return logger, nil
+	return logger, levels, nil
 }
 
 func getServerCertAndPool() (tls.Certificate, *x509.CertPool) {
@@ -116,7 +128,7 @@ func getServerCertAndPool() (tls.Certificate, *x509.CertPool) {
 	return serverCert, certPool
 }
 
-func configureLogger() *zap.Logger {
+func configureLogger() (*zap.Logger, *metricsserver.LevelController, Config) {
 	// Ensure the logs directory exists
 	err := os.MkdirAll("./logs", os.ModePerm)
 	if err != nil {
@@ -124,29 +136,24 @@ func configureLogger() *zap.Logger {
 	}
 
 	// Initialize logger based on configuration
-	loggerConfig, err := loadConfig(pathOfConfigFile)
+	config, err := loadConfig(pathOfConfigFile)
 	if err != nil {
 		log.Fatalf("Failed to load configs: %v", err)
 	}
 
-	logger, err := initLogger(loggerConfig)
+	logger, levels, err := initLogger(config.LoggerConfig)
 	if err != nil {
 		log.Fatalf("Failed to create logger: %v", err)
 	}
 
-	return logger
+	return logger, levels, config
 }
 
 func main() {
 	// Setup logger.
-	logger := configureLogger()
+	logger, levels, config := configureLogger()
 	defer logger.Sync()
 
-	listener, err := net.Listen("tcp", ":8080")
-	if err != nil {
-		logger.Fatal("Failed to listen", zap.Error(err))
-	}
-
 	serverCert, certPool := getServerCertAndPool()
 	// configuration of the certificates
 	conf := &tls.Config{
@@ -155,38 +162,54 @@ func main() {
 		ClientCAs:    certPool,
 	}
 
-	tlsCredentials := credentials.NewTLS(conf)
-	s := grpc.NewServer(
-		grpc.Creds(tlsCredentials),
-		grpc.KeepaliveEnforcementPolicy(kaep),
-		grpc.KeepaliveParams(kasp),
-		grpc.ChainUnaryInterceptor(
-			// Custom unary interceptor defined in middleware.go
-			UnaryInterceptorPrometheus,
-			// Recovery interceptor to handle panics
-			grpcmiddleware.ChainUnaryServer(
-				grpcrecovery.UnaryServerInterceptor(),
-			)),
-	)
-	// Initialize the server struct with the logger and cache.
-	srv := &server{
-		logger:                 logger,
-		lastErrorRequests:      NewCircularQueue(cacheSize),
-		lastSuccessfulRequests: NewCircularQueue(cacheSize),
-	}
-
-	pb.RegisterMetricsServiceServer(s, srv)
-	pv.RegisterVersionServiceServer(s, srv)
-	reflection.Register(s)
-
-	// Register prometheus for instrumentation.
-	http.Handle("/metrics", promhttp.Handler())
-	go func() {
-		http.ListenAndServe(":9091", nil)
-	}()
-
-	logger.Info("Server is listening on port 8080...")
-	if err := s.Serve(listener); err != nil {
-		logger.Fatal("Failed to serve", zap.Error(err))
+	opts := []metricsserver.Option{
+		metricsserver.WithLogger(logger),
+		metricsserver.WithLevelController(levels),
+		metricsserver.WithTLSConfig(conf),
+		metricsserver.WithAdmin(config.Admin),
+		metricsserver.WithAdmission(config.Admission),
+		metricsserver.WithMemoryLimit(config.MemoryLimit),
+		metricsserver.WithClientLabels(config.Metrics.ClientLabel),
+		metricsserver.WithAccessLog(config.AccessLog),
+		metricsserver.WithTracing(config.Tracing),
+		metricsserver.WithHealth(config.Health),
+		metricsserver.WithReadinessCheck("log_storage", metricsserver.WritableCheck("./logs"),
+			pb.MetricsService_ServiceDesc.ServiceName),
+		metricsserver.WithShutdown(config.Shutdown),
+	}
+	// Without listeners in the configuration, the server listens on :8080 with mTLS.
+	if len(config.Listeners) > 0 {
+		opts = append(opts, metricsserver.WithListeners(config.Listeners...))
+	}
+	srv, err := metricsserver.New(opts...)
+	if err != nil {
+		logger.Fatal("Failed to create server", zap.Error(err))
+	}
+
+	// Stop on SIGTERM or SIGINT.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if err := srv.Start(ctx); err != nil {
+		logger.Fatal("Failed to start server", zap.Error(err))
+	}
+
+	var serveErr error
+	select {
+	case <-srv.Done():
+		serveErr = srv.Err()
+		logger.Error("Failed to serve", zap.Error(serveErr))
+	case <-ctx.Done():
+		logger.Info("Received termination signal")
+	}
+
+	stopErr := srv.Stop(context.Background())
+	if stopErr != nil {
+		logger.Error("Failed to stop server", zap.Error(stopErr))
+	}
+	if serveErr != nil || stopErr != nil {
+		// os.Exit skips the deferred calls.
+		logger.Sync()
+		os.Exit(1)
 	}
 }
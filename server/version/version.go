@@ -1,15 +1,44 @@
 package version
 
 import (
+	"runtime"
+	"runtime/debug"
 	"time"
 )
 
+// Set with ldflags by build-script.sh.
 var (
 	Version        = "dev"
 	CommitHash     = "n/a"
 	BuildTimestamp = "n/a"
 )
 
+// startTime approximates when the process started, as package variables are initialised first.
+var startTime = time.Now()
+
+// Info describes the build of the server.
+type Info struct {
+	Version        string
+	CommitHash     string
+	BuildTimestamp int64 // Unix time, 0 when unknown
+	GoVersion      string
+	Modified       bool // Whether the source code had uncommitted changes
+	Modules        []Module
+	StartTime      time.Time
+}
+
+// Module is a Go module the server was built with.
+type Module struct {
+	Path    string
+	Version string
+	Sum     string
+}
+
+// Uptime returns how long the server has been running.
+func (i Info) Uptime() time.Duration {
+	return time.Since(i.StartTime)
+}
+
 func convertToTimestamp(date string) (timestamp int64, err error) {
 	timeStr := "2006-01-02T15:04:05"
 	t, err := time.Parse(timeStr, date)
@@ -19,10 +48,60 @@ func convertToTimestamp(date string) (timestamp int64, err error) {
 	timestamp = t.Unix()
 	return
 }
-func BuildVersion() (string, int64, error) {
-	timestamp, err := convertToTimestamp(BuildTimestamp)
-	if err != nil {
-		return "", 0, err
+
+// Get returns the build information of the server. The values set with ldflags take precedence,
+// and the build information embedded by the Go toolchain fills in the ones that are missing, so
+// binaries not built with build-script.sh are described too.
+func Get() Info {
+	buildInfo, _ := debug.ReadBuildInfo()
+	return newInfo(buildInfo)
+}
+
+func newInfo(buildInfo *debug.BuildInfo) Info {
+	info := Info{
+		Version:    Version,
+		CommitHash: CommitHash,
+		GoVersion:  runtime.Version(),
+		StartTime:  startTime,
+	}
+	if timestamp, err := convertToTimestamp(BuildTimestamp); err == nil {
+		info.BuildTimestamp = timestamp
+	}
+	if buildInfo == nil {
+		return info
+	}
+
+	if info.Version == "" || info.Version == "dev" {
+		info.Version = "dev"
+		// Binaries built with go install carry the version of the main module.
+		if v := buildInfo.Main.Version; v != "" && v != "(devel)" {
+			info.Version = v
+		}
+	}
+	if buildInfo.GoVersion != "" {
+		info.GoVersion = buildInfo.GoVersion
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.CommitHash == "n/a" {
+				info.CommitHash = setting.Value
+			}
+		case "vcs.time":
+			if t, err := time.Parse(time.RFC3339, setting.Value); err == nil && info.BuildTimestamp == 0 {
+				info.BuildTimestamp = t.Unix()
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	for _, dep := range buildInfo.Deps {
+		module := Module{Path: dep.Path, Version: dep.Version, Sum: dep.Sum}
+		// A replaced module is built from its replacement.
+		if dep.Replace != nil {
+			module.Version, module.Sum = dep.Replace.Version, dep.Replace.Sum
+		}
+		info.Modules = append(info.Modules, module)
 	}
-	return CommitHash, timestamp, nil
+	return info
 }
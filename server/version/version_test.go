@@ -1,6 +1,8 @@
 package version
 
 import (
+	"reflect"
+	"runtime/debug"
 	"testing"
 )
 
@@ -39,20 +41,83 @@ func TestConvertToTimestamp(t *testing.T) {
 	}
 }
 
-func TestBuildVersion(t *testing.T) {
-	Version = "test_version"
-	CommitHash = "test_commit"
-	BuildTimestamp = "2024-05-19T13:17:37"
+// setLdflags sets the variables set with ldflags for the duration of the test.
+func setLdflags(t *testing.T, version, commitHash, buildTimestamp string) {
+	oldVersion, oldCommitHash, oldBuildTimestamp := Version, CommitHash, BuildTimestamp
+	Version, CommitHash, BuildTimestamp = version, commitHash, buildTimestamp
+	t.Cleanup(func() {
+		Version, CommitHash, BuildTimestamp = oldVersion, oldCommitHash, oldBuildTimestamp
+	})
+}
+
+var testBuildInfo = &debug.BuildInfo{
+	GoVersion: "go1.22.3",
+	Main:      debug.Module{Path: "metrics", Version: "v1.2.3"},
+	Deps: []*debug.Module{
+		{Path: "google.golang.org/grpc", Version: "v1.64.0", Sum: "h1:grpc"},
+		{Path: "go.uber.org/zap", Version: "v1.27.0", Replace: &debug.Module{Path: "../zap", Version: "v1.27.1"}},
+	},
+	Settings: []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "0123456789abcdef"},
+		{Key: "vcs.time", Value: "2024-05-19T13:17:37Z"},
+		{Key: "vcs.modified", Value: "true"},
+	},
+}
+
+func TestNewInfo_Ldflags(t *testing.T) {
+	setLdflags(t, "test_version", "test_commit", "2024-05-20T10:00:00")
+
+	info := newInfo(testBuildInfo)
+
+	if info.Version != "test_version" {
+		t.Errorf("newInfo() version = %v, want %v", info.Version, "test_version")
+	}
+	if info.CommitHash != "test_commit" {
+		t.Errorf("newInfo() commit hash = %v, want %v", info.CommitHash, "test_commit")
+	}
+	if info.BuildTimestamp != 1716199200 {
+		t.Errorf("newInfo() timestamp = %v, want %v", info.BuildTimestamp, 1716199200)
+	}
+}
+
+func TestNewInfo_BuildInfoFallback(t *testing.T) {
+	setLdflags(t, "dev", "n/a", "n/a")
+
+	info := newInfo(testBuildInfo)
+
+	if info.Version != "v1.2.3" {
+		t.Errorf("newInfo() version = %v, want %v", info.Version, "v1.2.3")
+	}
+	if info.CommitHash != "0123456789abcdef" {
+		t.Errorf("newInfo() commit hash = %v, want %v", info.CommitHash, "0123456789abcdef")
+	}
+	if info.BuildTimestamp != 1716124657 {
+		t.Errorf("newInfo() timestamp = %v, want %v", info.BuildTimestamp, 1716124657)
+	}
+	if info.GoVersion != "go1.22.3" {
+		t.Errorf("newInfo() go version = %v, want %v", info.GoVersion, "go1.22.3")
+	}
+	if !info.Modified {
+		t.Errorf("newInfo() modified = false, want true")
+	}
+	wantModules := []Module{
+		{Path: "google.golang.org/grpc", Version: "v1.64.0", Sum: "h1:grpc"},
+		{Path: "go.uber.org/zap", Version: "v1.27.1"},
+	}
+	if !reflect.DeepEqual(info.Modules, wantModules) {
+		t.Errorf("newInfo() modules = %v, want %v", info.Modules, wantModules)
+	}
+}
 
-	wantVersion := "test_commit"
-	wantTimestamp := int64(1716124657)
+func TestNewInfo_NoBuildInfo(t *testing.T) {
+	setLdflags(t, "dev", "n/a", "n/a")
 
-	version, timestamp, _ := BuildVersion()
+	info := newInfo(nil)
 
-	if version != wantVersion {
-		t.Errorf("BuildVersion() version = %v, want %v", version, wantVersion)
+	if info.Version != "dev" || info.CommitHash != "n/a" || info.BuildTimestamp != 0 {
+		t.Errorf("newInfo() = %+v, want the defaults", info)
 	}
-	if timestamp != wantTimestamp {
-		t.Errorf("BuildVersion() timestamp = %v, want %v", timestamp, wantTimestamp)
+	if info.GoVersion == "" {
+		t.Errorf("newInfo() go version is empty")
 	}
 }